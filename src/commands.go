@@ -0,0 +1,128 @@
+package main
+
+import (
+	gen "allium/src/convert"
+	"allium/src/lex"
+	"allium/src/parse"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// runCommand runs a subcommand named by the first argument, reporting
+// whether there was one.
+func runCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+
+	var err error
+	switch args[0] {
+	case "tokens":
+		err = printTokens(args[1:])
+	case "ast":
+		err = printAst(args[1:])
+	case "view":
+		err = viewDocument(args[1:])
+	case "fmt":
+		err = formatCommand(args[1:])
+	case "lint":
+		err = lintCommand(args[1:])
+	default:
+		return false
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	return true
+}
+
+// printTokens prints the token stream of a file with the position of each
+// token.
+func printTokens(args []string) error {
+	flags := flag.NewFlagSet("tokens", flag.ExitOnError)
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("Usage: go run ./src tokens <source>")
+	}
+
+	data, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	lex.PrintTokens(lex.NewLexer(string(data)).Tokenize())
+	return nil
+}
+
+// printAst prints the node tree of a file with the source span of each
+// node.
+func printAst(args []string) error {
+	flags := flag.NewFlagSet("ast", flag.ExitOnError)
+	extensionsFlag := flags.String("extensions", "", "Comma separated Markdown extensions")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("Usage: go run ./src ast [--extensions=<names>] <source>")
+	}
+
+	extensions, err := parse.ParseExtensions(*extensionsFlag)
+	if err != nil {
+		return err
+	}
+
+	document, err := readDocument(flags.Arg(0), extensions, nil)
+	if err != nil {
+		return err
+	}
+
+	parse.PrintNodes([]parse.Node{document})
+	return nil
+}
+
+// viewDocument prints a file styled for reading in the terminal.
+func viewDocument(args []string) error {
+	flags := flag.NewFlagSet("view", flag.ExitOnError)
+	extensionsFlag := flags.String("extensions", "", "Comma separated Markdown extensions")
+	widthFlag := flags.Int("width", 0, "Wrap text at this width, the terminal width by default")
+	linkUrlsFlag := flags.Bool("link-urls", false, "Follow link text with the URL rather than making it a hyperlink")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("Usage: go run ./src view [--width=<n>] [--link-urls] [--extensions=<names>] <source>")
+	}
+
+	extensions, err := parse.ParseExtensions(*extensionsFlag)
+	if err != nil {
+		return err
+	}
+
+	document, err := readDocument(flags.Arg(0), extensions, nil)
+	if err != nil {
+		return err
+	}
+
+	generator := gen.NewTerminalGenerator(document)
+	generator.Width = *widthFlag
+	if *widthFlag == 0 {
+		generator.Width = outputWidth()
+	}
+	generator.Hyperlinks = !*linkUrlsFlag
+
+	_, err = os.Stdout.WriteString(generator.Terminal())
+	return err
+}
+
+// outputWidth is the width of the terminal on standard output, or failing
+// that of $COLUMNS, or 80.
+func outputWidth() int {
+	if width := terminalWidth(); width > 0 {
+		return width
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+
+	return 80
+}
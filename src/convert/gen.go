@@ -3,119 +3,260 @@ package gen
 import (
 	"allium/src/parse"
 	"fmt"
+	"html/template"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 type Generator struct {
-	Nodes        []parse.NodeInterface
+	Document     *parse.DocumentNode
 	HeaderCount  int
-	PreviousNode parse.NodeInterface
+	PreviousNode parse.Node
+
+	// TableStyleAlignment writes table cell alignment as an inline style
+	// rather than the align attribute, which is obsolete in HTML5.
+	TableStyleAlignment bool
+
+	// Slugify derives heading IDs from heading text, GitHubSlug by default.
+	Slugify SlugFunc
+
+	// TableOfContents adds a list of links to the headings between
+	// TocMinLevel and TocMaxLevel, in place of a "[TOC]" paragraph or at the
+	// start of the document.
+	TableOfContents bool
+	TocMinLevel     int
+	TocMaxLevel     int
+
+	// Standalone makes GenerateHtml write a complete HTML document from
+	// Template, or DefaultTemplate when it is nil, rather than a fragment.
+	Standalone  bool
+	Template    *template.Template
+	Stylesheets []string
+
+	// Theme names a built-in stylesheet for standalone output. It is inlined
+	// in a style element, or with LinkTheme written next to the output file
+	// and linked instead.
+	Theme     string
+	LinkTheme bool
+
+	// Highlight renders fenced code blocks that name a language, such as
+	// HighlightCode. Standalone output then also inlines HighlightCSS.
+	Highlight Highlighter
+
+	// Renderer writes the markup of each node, NewHtmlRenderer by default.
+	Renderer Renderer
+
+	out       strings.Builder
+	tight     []bool
+	headerIds map[*parse.HeaderNode]string
+
+	// checkbox is set while a task list checkbox waits to be written at the
+	// start of the next paragraph
+	checkbox *bool
 }
 
-func NewGenerator(nodes []parse.NodeInterface) Generator {
+func NewGenerator(document *parse.DocumentNode) Generator {
 	var gen = Generator{}
-	gen.Nodes = nodes
+	gen.Document = document
+	gen.Slugify = GitHubSlug
+	gen.TocMinLevel = 1
+	gen.TocMaxLevel = 6
+	gen.Renderer = NewHtmlRenderer()
 
 	return gen
 }
 
-func (g *Generator) GenerateHtml(filepath string) {
-	file, err := os.Create(filepath)
-	if err != nil {
-		panic(err)
+// Title returns the title given in the document front matter, if any.
+func (g *Generator) Title() string {
+	return g.Document.FrontMatter.Get("title")
+}
+
+func (g *Generator) GenerateHtml(outputPath string) error {
+	if g.Standalone && g.Theme != "" && g.LinkTheme {
+		css, err := ThemeCSS(g.Theme)
+		if err != nil {
+			return err
+		}
+
+		cssPath := filepath.Join(filepath.Dir(outputPath), themeFileName(g.Theme))
+		if err := os.WriteFile(cssPath, []byte(css), 0644); err != nil {
+			return err
+		}
 	}
-	defer file.Close()
 
-	for _, node := range g.Nodes {
-		g.convert_node(file, node)
+	output := g.Html()
+	if g.Standalone {
+		var err error
+		if output, err = g.HtmlDocument(); err != nil {
+			return err
+		}
 	}
+
+	return os.WriteFile(outputPath, []byte(output), 0644)
 }
 
-func (g *Generator) convert_node(file *os.File, node parse.NodeInterface) {
-	switch node := node.(type) {
-	case parse.HeaderNode:
-		g.HeaderCount++
+// Html renders the document and returns the resulting markup.
+func (g *Generator) Html() string {
+	g.out.Reset()
+	g.HeaderCount = 0
+	g.assignHeaderIds()
 
-		fmt.Fprintf(file, "<h%d id =\"%s-%d\">", node.Level, "header", g.HeaderCount)
-		for _, headerNode := range node.Content {
-			g.convert_node(file, headerNode)
-		}
-		fmt.Fprintf(file, "</h%d>\n", node.Level)
-	case parse.ParagraphNode:
-		fmt.Fprintf(file, "<p>")
-		for _, paragraphNode := range node.Content {
-			g.convert_node(file, paragraphNode)
-		}
-		fmt.Fprintf(file, "</p>\n")
-	case parse.ItalicNode:
-		fmt.Fprintf(file, "<em>")
-		for _, paragraphNode := range node.Nodes {
-			g.convert_node(file, paragraphNode)
-		}
-		fmt.Fprintf(file, "</em>")
+	if g.TableOfContents && !g.hasTocPlaceholder() {
+		g.convert_toc()
+	}
+	for _, node := range g.Document.Nodes {
+		g.convert_node(node)
+	}
+	g.convert_footnotes()
 
-	case parse.BoldNode:
-		fmt.Fprintf(file, "<strong>")
-		for _, paragraphNode := range node.Nodes {
-			g.convert_node(file, paragraphNode)
-		}
-		fmt.Fprintf(file, "</strong>")
-	case parse.WhiteSpaceNode:
-		fmt.Fprintf(file, " ")
-	case parse.TextNode:
-		fmt.Fprintf(file, node.Content)
-	case parse.NoNode:
-		fmt.Fprintf(file, "")
-	case parse.NewLineNode:
-		// if _, wasNewLine := g.previousNode.(NewLineNode); !wasNewLine {
-		// fmt.Fprintf(file, "<br>")
-		fmt.Fprintf(file, "\n")
-		// }
-	case parse.LinkNode:
-		fmt.Fprintf(file, "<a href=\"%s\">%s</a>\n", node.Link, node.LinkText)
-	case parse.ImageNode:
-		fmt.Fprintf(file, "<img src=\"%s\" alt=\"%s\">\n", node.Link, node.LinkText)
-	case parse.ListItemNode:
-		fmt.Fprintf(file, "<li>")
-		for _, listNode := range node.Nodes {
-			g.convert_node(file, listNode)
-		}
-		fmt.Fprintf(file, "</li>\n")
-	case parse.ListNode:
-		if node.IsOrdered {
-			fmt.Fprintf(file, "<ol>\n")
-		} else {
-			fmt.Fprintf(file, "<ul>\n")
+	return g.out.String()
+}
+
+// convert_footnotes writes the referenced footnotes in a section at the end
+// of the document, each with links back to where it was referenced.
+func (g *Generator) convert_footnotes() {
+	var footnotes []*parse.FootnoteDefinitionNode
+	for _, footnote := range g.Document.Footnotes {
+		if footnote.Index > 0 {
+			footnotes = append(footnotes, footnote)
 		}
-		for _, list := range node.Nodes {
-			g.convert_node(file, list)
+	}
+
+	if len(footnotes) == 0 {
+		return
+	}
+
+	g.CR()
+	g.Write("<section class=\"footnotes\">\n<ol>\n")
+	for _, footnote := range footnotes {
+		g.Write("<li id=\"fn-%d\">\n", footnote.Index)
+
+		last := len(footnote.Nodes) - 1
+		for i, node := range footnote.Nodes {
+			// back references go at the end of a closing paragraph
+			if paragraph, ok := node.(*parse.ParagraphNode); ok && i == last {
+				g.Write("<p>")
+				g.convert_nodes(paragraph.Content)
+				g.convert_backreferences(footnote)
+				g.Write("</p>\n")
+				continue
+			}
+
+			g.convert_node(node)
 		}
-		if node.IsOrdered {
-			fmt.Fprintf(file, "</ol>\n")
-		} else {
-			fmt.Fprintf(file, "</ul>\n")
+
+		if last < 0 {
+			g.convert_backreferences(footnote)
+		} else if _, ok := footnote.Nodes[last].(*parse.ParagraphNode); !ok {
+			g.CR()
+			g.Write("<p>")
+			g.convert_backreferences(footnote)
+			g.Write("</p>\n")
 		}
-	case parse.HorizontalRuleNode:
-		fmt.Fprintf(file, "<hr>\n")
-	case parse.BlockQuoteNode:
-		fmt.Fprintf(file, "<blockquote>\n")
-		for _, list := range node.Nodes {
-			g.convert_node(file, list)
+
+		g.CR()
+		g.Write("</li>\n")
+	}
+	g.Write("</ol>\n</section>\n")
+}
+
+func (g *Generator) convert_backreferences(footnote *parse.FootnoteDefinitionNode) {
+	for occurrence := 1; occurrence <= footnote.References; occurrence++ {
+		g.Write(" <a href=\"#%s\" class=\"footnote-backref\" aria-label=\"Back to reference %d\">↩", footnoteReferenceId(footnote.Index, occurrence), footnote.Index)
+		if occurrence > 1 {
+			g.Write("<sup>%d</sup>", occurrence)
 		}
-		fmt.Fprintf(file, "\n</blockquote>\n")
-	case parse.InlineCodeNode:
-		fmt.Fprintf(file, "<code>")
-		fmt.Fprintf(file, "%s", node.Content)
-		fmt.Fprintf(file, "</code>\n")
-	case parse.InlineCodeBlockNode:
-		fmt.Fprintf(file, "<pre>")
-		fmt.Fprintf(file, "<code>")
-		fmt.Fprintf(file, "%s", node.Content)
-		fmt.Fprintf(file, "</code>")
-		fmt.Fprintf(file, "</pre>")
-	default:
-		fmt.Printf("Unknown node type: %s", node)
+		g.Write("</a>")
 	}
+}
+
+func footnoteReferenceId(index int, occurrence int) string {
+	if occurrence == 1 {
+		return fmt.Sprintf("fnref-%d", index)
+	}
+	return fmt.Sprintf("fnref-%d-%d", index, occurrence)
+}
+
+func (g *Generator) Write(format string, args ...any) {
+	fmt.Fprintf(&g.out, format, args...)
+}
+
+// CR starts a new line unless the output is already at the start of one.
+func (g *Generator) CR() {
+	output := g.out.String()
+	if len(output) > 0 && !strings.HasSuffix(output, "\n") {
+		g.out.WriteString("\n")
+	}
+}
 
+// InTightList reports whether paragraphs are currently rendered without
+// their tags, as they are directly inside the items of a tight list.
+func (g *Generator) InTightList() bool {
+	return len(g.tight) > 0 && g.tight[len(g.tight)-1]
+}
+
+// HeaderId returns the ID given to a heading of the document.
+func (g *Generator) HeaderId(header *parse.HeaderNode) string {
+	return g.headerIds[header]
+}
+
+// RenderNode renders a node with the Renderer.
+func (g *Generator) RenderNode(node parse.Node) {
+	g.convert_node(node)
+}
+
+// RenderNodes renders each node with the Renderer.
+func (g *Generator) RenderNodes(nodes []parse.Node) {
+	g.convert_nodes(nodes)
+}
+
+// RenderBlocks renders the block children of a container, with paragraphs
+// left untagged when tight is set.
+func (g *Generator) RenderBlocks(nodes []parse.Node, tight bool) {
+	g.tight = append(g.tight, tight)
+	g.convert_nodes(nodes)
+	g.tight = g.tight[:len(g.tight)-1]
+}
+
+func (g *Generator) convert_nodes(nodes []parse.Node) {
+	for _, node := range nodes {
+		g.convert_node(node)
+	}
+}
+
+func (g *Generator) convert_node(node parse.Node) {
+	g.Renderer.RenderNode(g, node)
 	g.PreviousNode = node
 }
+
+func (g *Generator) highlight(code string, language string) (string, bool) {
+	if g.Highlight == nil || language == "" {
+		return "", false
+	}
+
+	return g.Highlight(code, language)
+}
+
+func isTaskList(list *parse.ListNode) bool {
+	for _, item := range list.Nodes {
+		if item, ok := item.(*parse.ListItemNode); ok && item.Checked != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+var htmlEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	"\"", "&quot;",
+)
+
+// EscapeHtml escapes the characters that are special in HTML text and
+// attribute values.
+func EscapeHtml(s string) string {
+	return htmlEscaper.Replace(s)
+}
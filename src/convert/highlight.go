@@ -0,0 +1,308 @@
+package gen
+
+import (
+	_ "embed"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Highlighter renders the code of a fenced block as HTML for the language
+// named by its info string. It returns false for languages it does not
+// handle, which are then written as plain escaped text.
+type Highlighter func(code string, language string) (string, bool)
+
+// HighlightCSS colours the classes HighlightCode wraps tokens in.
+//
+//go:embed highlight.css
+var HighlightCSS string
+
+type syntax struct {
+	keywords     map[string]bool
+	literals     map[string]bool
+	lineComments []string
+	blockComment [2]string
+	quotes       string
+	tripleQuotes bool
+	variables    bool
+	yamlKeys     bool
+	jsonKeys     bool
+	ignoreCase   bool
+	wordComments bool
+}
+
+func words(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(list) {
+		set[word] = true
+	}
+	return set
+}
+
+var goSyntax = &syntax{
+	keywords:     words("break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var"),
+	literals:     words("true false nil iota"),
+	lineComments: []string{"//"},
+	blockComment: [2]string{"/*", "*/"},
+	quotes:       "\"'`",
+}
+
+var jsonSyntax = &syntax{
+	literals: words("true false null"),
+	quotes:   "\"",
+	jsonKeys: true,
+}
+
+var yamlSyntax = &syntax{
+	literals:     words("true false null yes no on off"),
+	lineComments: []string{"#"},
+	quotes:       "\"'",
+	yamlKeys:     true,
+	wordComments: true,
+}
+
+var shellSyntax = &syntax{
+	keywords:     words("if then else elif fi case esac for while until do done in function return local export select"),
+	lineComments: []string{"#"},
+	quotes:       "\"'",
+	variables:    true,
+	wordComments: true,
+}
+
+var pythonSyntax = &syntax{
+	keywords:     words("and as assert async await break class continue def del elif else except finally for from global if import in is lambda nonlocal not or pass raise return try while with yield"),
+	literals:     words("True False None"),
+	lineComments: []string{"#"},
+	quotes:       "\"'",
+	tripleQuotes: true,
+}
+
+var sqlSyntax = &syntax{
+	keywords:     words("select from where and or not insert into values update set delete create table drop alter add index view join inner left right outer full on as group by order having limit offset distinct union all case when then else end is in like between exists primary key foreign references default asc desc"),
+	literals:     words("null true false"),
+	lineComments: []string{"--"},
+	blockComment: [2]string{"/*", "*/"},
+	quotes:       "'\"",
+	ignoreCase:   true,
+}
+
+var syntaxes = map[string]*syntax{
+	"go":     goSyntax,
+	"golang": goSyntax,
+	"json":   jsonSyntax,
+	"yaml":   yamlSyntax,
+	"yml":    yamlSyntax,
+	"sh":     shellSyntax,
+	"bash":   shellSyntax,
+	"shell":  shellSyntax,
+	"zsh":    shellSyntax,
+	"python": pythonSyntax,
+	"py":     pythonSyntax,
+	"sql":    sqlSyntax,
+}
+
+// HighlightCode is the built-in highlighter for Go, JSON, YAML, shell,
+// Python and SQL. Tokens are wrapped in spans with the hl-keyword,
+// hl-string, hl-comment, hl-number, hl-literal, hl-key and hl-variable
+// classes styled by HighlightCSS.
+func HighlightCode(code string, language string) (string, bool) {
+	syntax, ok := syntaxes[strings.ToLower(language)]
+	if !ok {
+		return "", false
+	}
+
+	var h highlighter
+	h.syntax = syntax
+	h.code = code
+	h.highlight()
+
+	return h.out.String(), true
+}
+
+type highlighter struct {
+	syntax *syntax
+	code   string
+	pos    int
+	out    strings.Builder
+}
+
+var yamlKey = regexp.MustCompile(`^([ \t]*(?:-[ \t]+)?)([^ \t#:\-"'][^:#\n]*?|"[^"\n]*"|'[^'\n]*')([ \t]*:)(?:[ \t]|$)`)
+
+func (h *highlighter) highlight() {
+	for h.pos < len(h.code) {
+		if h.syntax.yamlKeys && h.atLineStart() {
+			line, _, _ := strings.Cut(h.code[h.pos:], "\n")
+			if match := yamlKey.FindStringSubmatch(line); match != nil {
+				h.plain(match[1])
+				h.span("key", match[2])
+				h.plain(match[3])
+				h.pos += len(match[1]) + len(match[2]) + len(match[3])
+				continue
+			}
+		}
+
+		rest := h.code[h.pos:]
+		switch c, size := utf8.DecodeRuneInString(rest); {
+		case h.startsComment(rest):
+			h.comment(rest)
+		case strings.ContainsRune(h.syntax.quotes, c):
+			h.quoted(rest, c)
+		case h.syntax.variables && c == '$' && len(rest) > 1:
+			h.variable(rest)
+		case unicode.IsDigit(c) && !h.afterWord():
+			end := strings.IndexFunc(rest, func(r rune) bool {
+				return !unicode.IsDigit(r) && !unicode.IsLetter(r) && r != '.' && r != '_'
+			})
+			if end < 0 {
+				end = len(rest)
+			}
+			h.span("number", rest[:end])
+			h.pos += end
+		case unicode.IsLetter(c) || c == '_':
+			end := strings.IndexFunc(rest, func(r rune) bool {
+				return !unicode.IsDigit(r) && !unicode.IsLetter(r) && r != '_'
+			})
+			if end < 0 {
+				end = len(rest)
+			}
+			h.word(rest[:end])
+			h.pos += end
+		default:
+			h.plain(rest[:size])
+			h.pos += size
+		}
+	}
+}
+
+func (h *highlighter) atLineStart() bool {
+	return h.pos == 0 || h.code[h.pos-1] == '\n'
+}
+
+func (h *highlighter) afterWord() bool {
+	if h.pos == 0 {
+		return false
+	}
+	c, _ := utf8.DecodeLastRuneInString(h.code[:h.pos])
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_'
+}
+
+func (h *highlighter) startsComment(rest string) bool {
+	// shell and YAML only start a comment at the beginning of a word
+	if h.syntax.wordComments && h.pos > 0 && !unicode.IsSpace(rune(h.code[h.pos-1])) {
+		return false
+	}
+
+	for _, marker := range h.syntax.lineComments {
+		if strings.HasPrefix(rest, marker) {
+			return true
+		}
+	}
+
+	return h.syntax.blockComment[0] != "" && strings.HasPrefix(rest, h.syntax.blockComment[0])
+}
+
+func (h *highlighter) comment(rest string) {
+	end := -1
+	if open, close := h.syntax.blockComment[0], h.syntax.blockComment[1]; open != "" && strings.HasPrefix(rest, open) {
+		if i := strings.Index(rest[len(open):], close); i >= 0 {
+			end = len(open) + i + len(close)
+		}
+	} else {
+		end = strings.IndexByte(rest, '\n')
+	}
+	if end < 0 {
+		end = len(rest)
+	}
+
+	h.span("comment", rest[:end])
+	h.pos += end
+}
+
+// quoted scans a string literal. Strings other than backtick and triple
+// quoted ones end at the line end when they are not closed.
+func (h *highlighter) quoted(rest string, quote rune) {
+	delimiter := string(quote)
+	multiline := quote == '`'
+	if h.syntax.tripleQuotes && strings.HasPrefix(rest, strings.Repeat(delimiter, 3)) {
+		delimiter = strings.Repeat(delimiter, 3)
+		multiline = true
+	}
+
+	end := len(rest)
+	for i := len(delimiter); i < len(rest); i++ {
+		if rest[i] == '\\' && quote != '`' {
+			i++
+			continue
+		}
+		if rest[i] == '\n' && !multiline {
+			end = i
+			break
+		}
+		if strings.HasPrefix(rest[i:], delimiter) {
+			end = i + len(delimiter)
+			break
+		}
+	}
+	if end > len(rest) {
+		end = len(rest)
+	}
+
+	class := "string"
+	if h.syntax.jsonKeys && strings.HasPrefix(strings.TrimLeft(rest[end:], " \t"), ":") {
+		class = "key"
+	}
+
+	h.span(class, rest[:end])
+	h.pos += end
+}
+
+func (h *highlighter) variable(rest string) {
+	end := 1
+	switch {
+	case rest[1] == '{':
+		end = strings.IndexByte(rest, '}') + 1
+		if end == 0 {
+			end = len(rest)
+		}
+	case strings.ContainsRune("?#@*$!0123456789", rune(rest[1])):
+		end = 2
+	default:
+		for end < len(rest) && (rest[end] == '_' || unicode.IsLetter(rune(rest[end])) || unicode.IsDigit(rune(rest[end]))) {
+			end++
+		}
+	}
+
+	if end == 1 {
+		h.plain("$")
+		h.pos++
+		return
+	}
+
+	h.span("variable", rest[:end])
+	h.pos += end
+}
+
+func (h *highlighter) word(word string) {
+	key := word
+	if h.syntax.ignoreCase {
+		key = strings.ToLower(word)
+	}
+
+	switch {
+	case h.syntax.keywords[key]:
+		h.span("keyword", word)
+	case h.syntax.literals[key]:
+		h.span("literal", word)
+	default:
+		h.plain(word)
+	}
+}
+
+func (h *highlighter) span(class string, text string) {
+	h.out.WriteString("<span class=\"hl-" + class + "\">" + EscapeHtml(text) + "</span>")
+}
+
+func (h *highlighter) plain(text string) {
+	h.out.WriteString(EscapeHtml(text))
+}
@@ -0,0 +1,142 @@
+package gen
+
+import (
+	"allium/src/lex"
+	"allium/src/parse"
+	"strings"
+	"testing"
+)
+
+func TestHighlightCode(t *testing.T) {
+	tests := []struct {
+		language string
+		code     string
+		html     string
+	}{
+		{
+			language: "go",
+			code:     "func main() {\n\tif a < b && c > 0 { s := \"<a>\" } // x & y\n}\n",
+			html: "<span class=\"hl-keyword\">func</span> main() {\n" +
+				"\t<span class=\"hl-keyword\">if</span> a &lt; b &amp;&amp; c &gt; <span class=\"hl-number\">0</span> { s := <span class=\"hl-string\">&quot;&lt;a&gt;&quot;</span> } <span class=\"hl-comment\">// x &amp; y</span>\n" +
+				"}\n",
+		},
+		{
+			language: "json",
+			code:     "{\"a\": [1, true, null, \"<b>\"]}\n",
+			html:     "{<span class=\"hl-key\">&quot;a&quot;</span>: [<span class=\"hl-number\">1</span>, <span class=\"hl-literal\">true</span>, <span class=\"hl-literal\">null</span>, <span class=\"hl-string\">&quot;&lt;b&gt;&quot;</span>]}\n",
+		},
+		{
+			language: "yaml",
+			code:     "key: value # c\nlist:\n  - \"q\"\n  - 3\non: yes\n",
+			html: "<span class=\"hl-key\">key</span>: value <span class=\"hl-comment\"># c</span>\n" +
+				"<span class=\"hl-key\">list</span>:\n" +
+				"  - <span class=\"hl-string\">&quot;q&quot;</span>\n" +
+				"  - <span class=\"hl-number\">3</span>\n" +
+				"<span class=\"hl-key\">on</span>: <span class=\"hl-literal\">yes</span>\n",
+		},
+		{
+			language: "bash",
+			code:     "if [ \"$x\" ]; then echo ${y} > f; fi # c\n",
+			html:     "<span class=\"hl-keyword\">if</span> [ <span class=\"hl-string\">&quot;$x&quot;</span> ]; <span class=\"hl-keyword\">then</span> echo <span class=\"hl-variable\">${y}</span> &gt; f; <span class=\"hl-keyword\">fi</span> <span class=\"hl-comment\"># c</span>\n",
+		},
+		{
+			language: "python",
+			code:     "def f(a):\n    \"\"\"doc <b>\"\"\"\n    return None # c\n",
+			html: "<span class=\"hl-keyword\">def</span> f(a):\n" +
+				"    <span class=\"hl-string\">&quot;&quot;&quot;doc &lt;b&gt;&quot;&quot;&quot;</span>\n" +
+				"    <span class=\"hl-keyword\">return</span> <span class=\"hl-literal\">None</span> <span class=\"hl-comment\"># c</span>\n",
+		},
+		{
+			language: "SQL",
+			code:     "SELECT a FROM t WHERE b < 'x' -- c\n",
+			html:     "<span class=\"hl-keyword\">SELECT</span> a <span class=\"hl-keyword\">FROM</span> t <span class=\"hl-keyword\">WHERE</span> b &lt; <span class=\"hl-string\">'x'</span> <span class=\"hl-comment\">-- c</span>\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.language, func(t *testing.T) {
+			html, ok := HighlightCode(test.code, test.language)
+			if !ok {
+				t.Fatalf("%s was not highlighted", test.language)
+			}
+			if html != test.html {
+				t.Errorf("got\n%s\nwant\n%s", html, test.html)
+			}
+		})
+	}
+}
+
+func TestHighlightUnknownLanguage(t *testing.T) {
+	for _, language := range []string{"", "brainfuck", "go2"} {
+		if html, ok := HighlightCode("a < b", language); ok || html != "" {
+			t.Errorf("%q was highlighted as %q", language, html)
+		}
+	}
+}
+
+func renderHighlighted(source string) Generator {
+	parser := parse.NewParser(lex.NewLexer(source).Tokenize())
+	generator := NewGenerator(parser.Parse())
+	generator.Highlight = HighlightCode
+
+	return generator
+}
+
+func TestRenderHighlighted(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		html     string
+	}{
+		{
+			name:     "known language",
+			markdown: "```go title=\"x\"\nx := \"<\" // &\n```\n",
+			html:     "<pre><code class=\"language-go\">x := <span class=\"hl-string\">&quot;&lt;&quot;</span> <span class=\"hl-comment\">// &amp;</span>\n</code></pre>\n",
+		},
+		{
+			name:     "unknown language",
+			markdown: "```cobol\nIF A < B & \"C\"\n```\n",
+			html:     "<pre><code class=\"language-cobol\">IF A &lt; B &amp; &quot;C&quot;\n</code></pre>\n",
+		},
+		{
+			name:     "no language",
+			markdown: "```\nif a < b\n```\n",
+			html:     "<pre><code>if a &lt; b\n</code></pre>\n",
+		},
+		{
+			name:     "indented code",
+			markdown: "    func <x>\n",
+			html:     "<pre><code>func &lt;x&gt;\n</code></pre>\n",
+		},
+		{
+			name:     "language needing escaping",
+			markdown: "```a\"<b>\nc\n```\n",
+			html:     "<pre><code class=\"language-a&quot;&lt;b&gt;\">c\n</code></pre>\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			generator := renderHighlighted(test.markdown)
+			if html := generator.Html(); html != test.html {
+				t.Errorf("got\n%s\nwant\n%s", html, test.html)
+			}
+		})
+	}
+}
+
+func TestHighlightStyle(t *testing.T) {
+	generator := renderHighlighted("a\n")
+	document, err := generator.HtmlDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(document, "<style>\n"+HighlightCSS+"</style>") {
+		t.Errorf("the highlight stylesheet was not inlined:\n%s", document)
+	}
+
+	generator.Highlight = nil
+	if document, _ := generator.HtmlDocument(); strings.Contains(document, "<style>") {
+		t.Errorf("a stylesheet was inlined without highlighting:\n%s", document)
+	}
+}
@@ -0,0 +1,757 @@
+package gen
+
+import (
+	"allium/src/parse"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// HeadingStyle selects how the Markdown generator writes headings.
+type HeadingStyle int
+
+const (
+	// ATXHeadings writes every heading with leading "#" characters.
+	ATXHeadings HeadingStyle = iota
+	// SetextHeadings underlines level 1 and 2 headings with "=" and "-",
+	// falling back to ATX for the other levels.
+	SetextHeadings
+)
+
+// MarkdownGenerator writes a document back out as CommonMark in a single
+// consistent style. Link reference definitions have already been resolved
+// by the parser, so links are always written inline, and indented code is
+// written as fenced code.
+type MarkdownGenerator struct {
+	Document *parse.DocumentNode
+
+	// BulletChar marks bullet list items: '-', '*' or '+'.
+	BulletChar byte
+
+	// EmphasisChar delimits emphasis, and doubled, strong emphasis: '*' or
+	// '_'. Emphasis inside a word always uses '*', as '_' cannot.
+	EmphasisChar byte
+
+	HeadingStyle HeadingStyle
+
+	// LineWidth wraps paragraphs at this many columns, including the
+	// indentation of their containers. Zero keeps the line breaks of the
+	// source.
+	LineWidth int
+}
+
+func NewMarkdownGenerator(document *parse.DocumentNode) MarkdownGenerator {
+	var gen = MarkdownGenerator{}
+	gen.Document = document
+	gen.BulletChar = '-'
+	gen.EmphasisChar = '*'
+	gen.HeadingStyle = ATXHeadings
+
+	return gen
+}
+
+func (g *MarkdownGenerator) GenerateMarkdown(outputPath string) error {
+	return os.WriteFile(outputPath, []byte(g.Markdown()), 0644)
+}
+
+// Markdown renders the document and returns the resulting Markdown.
+func (g *MarkdownGenerator) Markdown() string {
+	var sections []string
+
+	if frontMatter := g.Document.FrontMatter; frontMatter != nil {
+		fence := "---"
+		if frontMatter.Format == "toml" {
+			fence = "+++"
+		}
+		if frontMatter.Raw == "" {
+			sections = append(sections, fence+"\n"+fence)
+		} else {
+			sections = append(sections, fence+"\n"+frontMatter.Raw+"\n"+fence)
+		}
+	}
+
+	if lines := g.convert_blocks(g.Document.Nodes, false, g.LineWidth); len(lines) > 0 {
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+
+	for _, footnote := range g.Document.Footnotes {
+		lines := g.convert_blocks(footnote.Nodes, false, narrow(g.LineWidth, 4))
+		sections = append(sections, strings.Join(prefixLines(lines, "[^"+footnote.Label+"]: ", "    "), "\n"))
+	}
+
+	if len(sections) == 0 {
+		return ""
+	}
+
+	return strings.Join(sections, "\n\n") + "\n"
+}
+
+// convert_blocks renders the block children of a container as lines,
+// separated by blank lines unless the container is tight.
+func (g *MarkdownGenerator) convert_blocks(nodes []parse.Node, tight bool, width int) []string {
+	var lines []string
+	var previous parse.Node
+	var previousMarker byte
+
+	for i, node := range nodes {
+		var block []string
+		if list, ok := node.(*parse.ListNode); ok {
+			// consecutive lists only stay apart when their markers differ
+			var avoid byte
+			if previousList, ok := previous.(*parse.ListNode); ok && previousList.IsOrdered == list.IsOrdered {
+				avoid = previousMarker
+			}
+			block, previousMarker = g.convert_list(list, avoid, width)
+		} else {
+			block = g.convert_block(node, previous, tight, width)
+		}
+
+		if i > 0 && !tight {
+			lines = append(lines, "")
+		}
+		lines = append(lines, block...)
+		previous = node
+	}
+
+	return lines
+}
+
+func (g *MarkdownGenerator) convert_block(node parse.Node, previous parse.Node, tight bool, width int) []string {
+	switch node := node.(type) {
+	case *parse.ParagraphNode:
+		return g.convert_inlines(node.Content, 0).wrap(width)
+	case *parse.HeaderNode:
+		return g.convert_header(node, tight)
+	case *parse.HorizontalRuleNode:
+		// "---" straight after a paragraph would underline it as a heading
+		if _, ok := previous.(*parse.ParagraphNode); ok && tight {
+			return []string{"***"}
+		}
+		return []string{"---"}
+	case *parse.InlineCodeBlockNode:
+		return convert_code_block(node)
+	case *parse.HtmlBlockNode:
+		return strings.Split(strings.TrimSuffix(node.Content, "\n"), "\n")
+	case *parse.BlockQuoteNode:
+		lines := g.convert_blocks(node.Nodes, false, narrow(width, 2))
+		if len(lines) == 0 {
+			return []string{">"}
+		}
+		return prefixLines(lines, "> ", "> ")
+	case *parse.TableNode:
+		return g.convert_table(node)
+	case *parse.DefinitionListNode:
+		return g.convert_definition_list(node, width)
+	}
+
+	return nil
+}
+
+func (g *MarkdownGenerator) convert_header(header *parse.HeaderNode, tight bool) []string {
+	inline := g.convert_inlines(header.Content, 0)
+
+	// setext content would join a paragraph right above it in a tight list
+	if g.HeadingStyle == SetextHeadings && header.Level <= 2 && !tight && len(inline.text) > 0 && header.ID == "" {
+		lines := inline.wrap(0)
+		underline := "="
+		if header.Level == 2 {
+			underline = "-"
+		}
+		width := 3
+		for _, line := range lines {
+			width = max(width, utf8.RuneCountInString(line))
+		}
+		return append(lines, strings.Repeat(underline, width))
+	}
+
+	text := inline.line()
+	// a trailing "#" would be taken as the closing sequence
+	if strings.HasSuffix(text, "#") {
+		text = text[:len(text)-1] + "\\#"
+	}
+
+	line := strings.Repeat("#", header.Level)
+	if text != "" {
+		line += " " + text
+	}
+	if header.ID != "" {
+		line += " {#" + header.ID + "}"
+	}
+
+	return []string{line}
+}
+
+func convert_code_block(code *parse.InlineCodeBlockNode) []string {
+	fenceChar := "`"
+	if strings.Contains(code.Info, "`") {
+		fenceChar = "~"
+	}
+	fence := strings.Repeat(fenceChar, max(3, longestRun(code.Content, fenceChar[0])+1))
+
+	lines := []string{fence + code.Info}
+	if code.Content != "" {
+		lines = append(lines, strings.Split(strings.TrimSuffix(code.Content, "\n"), "\n")...)
+	}
+
+	return append(lines, fence)
+}
+
+// convert_list renders a list with a marker other than avoid, returning the
+// lines along with the bullet or delimiter used.
+func (g *MarkdownGenerator) convert_list(list *parse.ListNode, avoid byte, width int) ([]string, byte) {
+	marker := g.BulletChar
+	if list.IsOrdered {
+		marker = '.'
+		if list.Marker == ")" {
+			marker = ')'
+		}
+	}
+	if marker == avoid {
+		marker = alternateMarker(marker)
+	}
+
+	var lines []string
+	for i, node := range list.Nodes {
+		item, ok := node.(*parse.ListItemNode)
+		if !ok {
+			continue
+		}
+
+		prefix := string(marker)
+		if list.IsOrdered {
+			prefix = fmt.Sprintf("%d%c", list.Start+i, marker)
+		}
+		indent := strings.Repeat(" ", len(prefix)+1)
+
+		content := g.convert_blocks(item.Nodes, list.IsTight, narrow(width, len(indent)))
+		if len(item.Nodes) > 0 && !list.IsOrdered {
+			// a rule of the bullet character would read as a rule in place
+			// of the whole list
+			if _, ok := item.Nodes[0].(*parse.HorizontalRuleNode); ok && content[0][0] == marker {
+				content[0] = strings.Repeat(string(alternateMarker(marker)), 3)
+			}
+		}
+		if item.Checked != nil && len(content) > 0 {
+			if *item.Checked {
+				content[0] = "[x] " + content[0]
+			} else {
+				content[0] = "[ ] " + content[0]
+			}
+		}
+
+		if i > 0 && !list.IsTight {
+			lines = append(lines, "")
+		}
+		if len(content) == 0 {
+			lines = append(lines, prefix)
+			continue
+		}
+		lines = append(lines, prefixLines(content, prefix+" ", indent)...)
+	}
+
+	return lines, marker
+}
+
+func alternateMarker(marker byte) byte {
+	switch marker {
+	case '.':
+		return ')'
+	case ')':
+		return '.'
+	case '-':
+		return '*'
+	}
+	return '-'
+}
+
+func (g *MarkdownGenerator) convert_table(table *parse.TableNode) []string {
+	var rows [][]string
+	var alignments []string
+	for _, node := range table.Nodes {
+		row, ok := node.(*parse.TableRowNode)
+		if !ok {
+			continue
+		}
+
+		var cells []string
+		for i, node := range row.Nodes {
+			cell, ok := node.(*parse.TableCellNode)
+			if !ok {
+				continue
+			}
+			text := g.convert_inlines(cell.Content, 0).line()
+			cells = append(cells, strings.ReplaceAll(text, "|", "\\|"))
+			if i >= len(alignments) {
+				alignments = append(alignments, cell.Alignment)
+			}
+		}
+		rows = append(rows, cells)
+	}
+
+	widths := make([]int, len(alignments))
+	for i := range widths {
+		widths[i] = 3
+		for _, row := range rows {
+			if i < len(row) {
+				widths[i] = max(widths[i], utf8.RuneCountInString(row[i]))
+			}
+		}
+	}
+
+	var lines []string
+	for r, row := range rows {
+		var line strings.Builder
+		line.WriteString("|")
+		for i, width := range widths {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			line.WriteString(" " + padCell(cell, width, alignments[i]) + " |")
+		}
+		lines = append(lines, line.String())
+
+		if r == 0 {
+			var delimiter strings.Builder
+			delimiter.WriteString("|")
+			for i, width := range widths {
+				delimiter.WriteString(" " + delimiterCell(width, alignments[i]) + " |")
+			}
+			lines = append(lines, delimiter.String())
+		}
+	}
+
+	return lines
+}
+
+func padCell(cell string, width int, alignment string) string {
+	padding := width - utf8.RuneCountInString(cell)
+	switch alignment {
+	case "right":
+		return strings.Repeat(" ", padding) + cell
+	case "center":
+		return strings.Repeat(" ", padding/2) + cell + strings.Repeat(" ", padding-padding/2)
+	}
+	return cell + strings.Repeat(" ", padding)
+}
+
+func delimiterCell(width int, alignment string) string {
+	switch alignment {
+	case "left":
+		return ":" + strings.Repeat("-", width-1)
+	case "right":
+		return strings.Repeat("-", width-1) + ":"
+	case "center":
+		return ":" + strings.Repeat("-", width-2) + ":"
+	}
+	return strings.Repeat("-", width)
+}
+
+func (g *MarkdownGenerator) convert_definition_list(list *parse.DefinitionListNode, width int) []string {
+	var lines []string
+	var previous parse.Node
+
+	for _, node := range list.Nodes {
+		switch node := node.(type) {
+		case *parse.TermNode:
+			// a term after a definition starts a new paragraph of terms
+			if _, ok := previous.(*parse.DefinitionNode); ok {
+				lines = append(lines, "")
+			}
+			lines = append(lines, g.convert_inlines(node.Content, 0).line())
+		case *parse.DefinitionNode:
+			if !node.IsTight {
+				lines = append(lines, "")
+			}
+			content := g.convert_blocks(node.Nodes, node.IsTight, narrow(width, 2))
+			if len(content) == 0 {
+				lines = append(lines, ":")
+				break
+			}
+			lines = append(lines, prefixLines(content, ": ", "  ")...)
+		}
+		previous = node
+	}
+
+	return lines
+}
+
+// markdownInline is rendered inline content. The spaces that wrapping may
+// break at and the hard breaks are kept as offsets into the text rather
+// than as marker characters, which the content itself could contain. Soft
+// breaks are written as "\n".
+type markdownInline struct {
+	text   []byte
+	spaces []int
+	breaks []int
+}
+
+func (m *markdownInline) WriteString(s string) {
+	m.text = append(m.text, s...)
+}
+
+// writeSpace writes a space that wrapping may break at.
+func (m *markdownInline) writeSpace() {
+	m.spaces = append(m.spaces, len(m.text))
+	m.text = append(m.text, ' ')
+}
+
+func (m *markdownInline) writeBreak() {
+	m.breaks = append(m.breaks, len(m.text))
+}
+
+// writeInline appends other, keeping its spaces and breaks.
+func (m *markdownInline) writeInline(other *markdownInline) {
+	for _, space := range other.spaces {
+		m.spaces = append(m.spaces, len(m.text)+space)
+	}
+	for _, lineBreak := range other.breaks {
+		m.breaks = append(m.breaks, len(m.text)+lineBreak)
+	}
+	m.text = append(m.text, other.text...)
+}
+
+// convert_inlines renders inline nodes. outer is the delimiter of the
+// emphasis directly around them, which nested emphasis alternates with.
+func (g *MarkdownGenerator) convert_inlines(nodes []parse.Node, outer byte) *markdownInline {
+	var out markdownInline
+
+	for i, node := range nodes {
+		switch node := node.(type) {
+		case *parse.TextNode:
+			writeEscaped(&out, node.Content)
+		case *parse.ItalicNode:
+			delimiter := g.emphasisChar(nodes, i, outer)
+			out.WriteString(string(delimiter))
+			out.writeInline(g.convert_inlines(node.Nodes, delimiter))
+			out.WriteString(string(delimiter))
+		case *parse.BoldNode:
+			delimiter := g.emphasisChar(nodes, i, outer)
+			strong := strings.Repeat(string(delimiter), 2)
+			out.WriteString(strong)
+			out.writeInline(g.convert_inlines(node.Nodes, delimiter))
+			out.WriteString(strong)
+		case *parse.StrikethroughNode:
+			out.WriteString("~~")
+			out.writeInline(g.convert_inlines(node.Nodes, 0))
+			out.WriteString("~~")
+		case *parse.InlineCodeNode:
+			out.WriteString(codeSpan(node.Content))
+		case *parse.LinkNode:
+			if autolink, ok := autolinkText(node); ok {
+				out.WriteString("<" + autolink + ">")
+				break
+			}
+			// a "!" right before the link would make it an image
+			if n := len(out.text); n > 0 && out.text[n-1] == '!' {
+				out.text = append(out.text[:n-1], "\\!"...)
+			}
+			out.WriteString("[")
+			out.writeInline(g.convert_inlines(node.Nodes, 0))
+			out.WriteString("](" + linkDestination(node.Link, node.Title) + ")")
+		case *parse.ImageNode:
+			var alt markdownInline
+			writeEscaped(&alt, node.LinkText)
+			out.WriteString("![" + string(alt.text) + "](" + linkDestination(node.Link, node.Title) + ")")
+		case *parse.HtmlInlineNode:
+			out.WriteString(node.Content)
+		case *parse.FootnoteReferenceNode:
+			out.WriteString("[^" + node.Label + "]")
+		case *parse.NewLineNode:
+			out.WriteString("\n")
+		case *parse.LineBreakNode:
+			out.writeBreak()
+		}
+	}
+
+	return &out
+}
+
+// emphasisChar picks the delimiter for the emphasis at nodes[i]. Emphasis
+// nested in emphasis takes the other delimiter from the one around it, so
+// "<em><em>x</em></em>" is written "*_x_*" rather than "**x**". '_' gives
+// way to '*' inside a word, where it cannot delimit emphasis, and emphasis
+// around such a '*' at either end of it takes '_' instead where it can.
+func (g *MarkdownGenerator) emphasisChar(nodes []parse.Node, i int, outer byte) byte {
+	if !underscoreFits(nodes, i) {
+		return '*'
+	}
+
+	delimiter := g.EmphasisChar
+	if outer != 0 {
+		delimiter = alternateEmphasis(outer)
+	}
+	if delimiter == '_' {
+		return delimiter
+	}
+
+	children := emphasisChildren(nodes[i])
+	atEdge := i == 0 || i == len(nodes)-1
+	if len(children) > 0 && !(outer == '_' && atEdge) &&
+		(!underscoreFits(children, 0) || !underscoreFits(children, len(children)-1)) {
+		return '_'
+	}
+
+	return delimiter
+}
+
+func emphasisChildren(node parse.Node) []parse.Node {
+	switch node := node.(type) {
+	case *parse.ItalicNode:
+		return node.Nodes
+	case *parse.BoldNode:
+		return node.Nodes
+	}
+	return nil
+}
+
+// underscoreFits reports whether '_' can delimit the emphasis at nodes[i],
+// which it cannot with a letter or digit of the text next to it on either
+// side. It is true of nodes that are not emphasis.
+func underscoreFits(nodes []parse.Node, i int) bool {
+	if emphasisChildren(nodes[i]) == nil {
+		return true
+	}
+
+	if i > 0 {
+		if text, ok := nodes[i-1].(*parse.TextNode); ok {
+			last, _ := utf8.DecodeLastRuneInString(text.Content)
+			if isWordRune(last) {
+				return false
+			}
+		}
+	}
+	if i+1 < len(nodes) {
+		if text, ok := nodes[i+1].(*parse.TextNode); ok {
+			first, _ := utf8.DecodeRuneInString(text.Content)
+			if isWordRune(first) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func alternateEmphasis(delimiter byte) byte {
+	if delimiter == '*' {
+		return '_'
+	}
+	return '*'
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+var entityLike = regexp.MustCompile(`^&(?:#[xX][0-9a-fA-F]{1,6}|#[0-9]{1,7}|[A-Za-z][A-Za-z0-9]{1,31});`)
+
+// writeEscaped writes text with the characters that could start inline
+// markup backslash-escaped, and its spaces breakable.
+func writeEscaped(out *markdownInline, text string) {
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch c {
+		case '\\', '`', '*', '[', ']', '<', '~':
+			out.text = append(out.text, '\\')
+		case '_':
+			// intraword underscores never delimit emphasis
+			before, _ := utf8.DecodeLastRuneInString(text[:i])
+			after, _ := utf8.DecodeRuneInString(text[i+1:])
+			if !isWordRune(before) || !isWordRune(after) {
+				out.text = append(out.text, '\\')
+			}
+		case '&':
+			if entityLike.MatchString(text[i:]) {
+				out.text = append(out.text, '\\')
+			}
+		case ' ':
+			out.writeSpace()
+			continue
+		}
+		out.text = append(out.text, c)
+	}
+}
+
+var orderedListStart = regexp.MustCompile(`^([0-9]{1,9})([.)])`)
+
+// escapeLineStart escapes the start of a line of paragraph text that would
+// otherwise be read as the start of a block.
+func escapeLineStart(line string) string {
+	if line == "" {
+		return line
+	}
+
+	if strings.IndexByte("#>-+=:|", line[0]) >= 0 {
+		return "\\" + line
+	}
+	if match := orderedListStart.FindStringSubmatchIndex(line); match != nil {
+		return line[:match[3]] + "\\" + line[match[3]:]
+	}
+
+	return line
+}
+
+// wrap splits the content into lines, filling lines up to width at its
+// breakable spaces when width is positive. Hard breaks end their line with
+// a backslash.
+func (m *markdownInline) wrap(width int) []string {
+	var lines []string
+
+	start, spaces := 0, m.spaces
+	for b := 0; b <= len(m.breaks); b++ {
+		end := len(m.text)
+		if b < len(m.breaks) {
+			end = m.breaks[b]
+		}
+
+		var segmentLines []string
+		if width <= 0 {
+			segmentLines = strings.Split(string(m.text[start:end]), "\n")
+		} else {
+			// words end at breakable spaces and soft breaks
+			var words []string
+			wordStart := start
+			for i := start; i <= end; i++ {
+				atSpace := i < end && len(spaces) > 0 && spaces[0] == i
+				if atSpace {
+					spaces = spaces[1:]
+				}
+				if i < end && !atSpace && m.text[i] != '\n' {
+					continue
+				}
+				if i > wordStart {
+					words = append(words, string(m.text[wordStart:i]))
+				}
+				wordStart = i + 1
+			}
+
+			line := ""
+			for _, word := range words {
+				switch {
+				case line == "":
+					line = word
+				case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) > width:
+					segmentLines = append(segmentLines, line)
+					line = word
+				default:
+					line += " " + word
+				}
+			}
+			segmentLines = append(segmentLines, line)
+		}
+
+		for i, line := range segmentLines {
+			segmentLines[i] = escapeLineStart(line)
+		}
+		if b < len(m.breaks) {
+			segmentLines[len(segmentLines)-1] += "\\"
+		}
+		lines = append(lines, segmentLines...)
+		start = end
+	}
+
+	return lines
+}
+
+// line joins the content onto a single line.
+func (m *markdownInline) line() string {
+	return strings.Join(m.wrap(0), " ")
+}
+
+// codeSpan wraps code in enough backticks that none inside can close it,
+// padding it with spaces where the parser would otherwise strip them.
+func codeSpan(code string) string {
+	fence := strings.Repeat("`", longestRun(code, '`')+1)
+	if code != "" && (code[0] == '`' || code[len(code)-1] == '`' ||
+		(code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "")) {
+		return fence + " " + code + " " + fence
+	}
+
+	return fence + code + fence
+}
+
+var autolinkScheme = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9.+-]{1,31}:[^\s<>]*$`)
+
+// autolinkText returns the text of a link that can be written as an
+// autolink, which is when its text is its destination.
+func autolinkText(link *parse.LinkNode) (string, bool) {
+	if len(link.Nodes) != 1 || link.Title != "" {
+		return "", false
+	}
+
+	text, ok := link.Nodes[0].(*parse.TextNode)
+	if !ok {
+		return "", false
+	}
+
+	if text.Content == link.Link && autolinkScheme.MatchString(link.Link) {
+		return text.Content, true
+	}
+	if "mailto:"+text.Content == link.Link && !strings.ContainsAny(text.Content, " <>\\") {
+		return text.Content, true
+	}
+
+	return "", false
+}
+
+// linkDestination writes the destination and optional title of a link.
+func linkDestination(link string, title string) string {
+	destination := link
+	if link == "" || strings.ContainsAny(link, " <>") {
+		destination = "<" + strings.NewReplacer("<", "\\<", ">", "\\>").Replace(link) + ">"
+	} else if strings.ContainsAny(link, "()") {
+		destination = strings.NewReplacer("(", "\\(", ")", "\\)").Replace(link)
+	}
+
+	if title == "" {
+		return destination
+	}
+
+	return destination + " \"" + strings.NewReplacer("\\", "\\\\", "\"", "\\\"").Replace(title) + "\""
+}
+
+func longestRun(s string, c byte) int {
+	longest, run := 0, 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == c {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+
+	return longest
+}
+
+// prefixLines puts first in front of the first line and rest in front of
+// the others, leaving blank lines without trailing spaces.
+func prefixLines(lines []string, first string, rest string) []string {
+	prefixed := make([]string, len(lines))
+	for i, line := range lines {
+		prefix := rest
+		if i == 0 {
+			prefix = first
+		}
+		if line == "" {
+			prefix = strings.TrimRight(prefix, " ")
+		}
+		prefixed[i] = prefix + line
+	}
+
+	return prefixed
+}
+
+// narrow reduces a line width by the indentation of a container, keeping
+// zero as no limit.
+func narrow(width int, indent int) int {
+	if width <= 0 {
+		return 0
+	}
+
+	return max(width-indent, 1)
+}
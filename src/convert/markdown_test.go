@@ -0,0 +1,135 @@
+package gen
+
+import (
+	"allium/src/lex"
+	"allium/src/parse"
+	"strings"
+	"testing"
+)
+
+func TestMarkdownListStartingWithRule(t *testing.T) {
+	source := "- ***\n- b\n\n---\n\n1. ---\n2. c\n"
+
+	for _, bullet := range []byte{'-', '*', '+'} {
+		generator := NewMarkdownGenerator(parse.NewParser(lex.NewLexer(source).Tokenize()).Parse())
+		generator.BulletChar = bullet
+		markdown := generator.Markdown()
+
+		if before, after := renderHtml(source, 0), renderHtml(markdown, 0); before != after {
+			t.Errorf("bullet %c: %q renders\n%s\nwant\n%s", bullet, markdown, after, before)
+		}
+	}
+}
+
+// paragraphOf wraps inline nodes in a document of one paragraph.
+func paragraphOf(nodes ...parse.Node) *parse.DocumentNode {
+	var paragraph parse.ParagraphNode
+	paragraph.Content = nodes
+	return &parse.DocumentNode{Nodes: []parse.Node{&paragraph}}
+}
+
+func textNode(content string) *parse.TextNode {
+	return &parse.TextNode{Content: content}
+}
+
+func TestMarkdownNestedEmphasis(t *testing.T) {
+	tests := []struct {
+		name     string
+		document *parse.DocumentNode
+		markdown string
+	}{
+		{
+			name:     "emphasis in emphasis",
+			document: paragraphOf(&parse.ItalicNode{Nodes: []parse.Node{&parse.ItalicNode{Nodes: []parse.Node{textNode("x")}}}}),
+			markdown: "*_x_*\n",
+		},
+		{
+			name:     "emphasis in the middle of emphasis",
+			document: paragraphOf(&parse.ItalicNode{Nodes: []parse.Node{textNode("a "), &parse.ItalicNode{Nodes: []parse.Node{textNode("b")}}, textNode(" c")}}),
+			markdown: "*a _b_ c*\n",
+		},
+		{
+			name:     "strong emphasis in emphasis",
+			document: paragraphOf(&parse.ItalicNode{Nodes: []parse.Node{&parse.BoldNode{Nodes: []parse.Node{textNode("x")}}}}),
+			markdown: "*__x__*\n",
+		},
+		{
+			name:     "three levels",
+			document: paragraphOf(&parse.ItalicNode{Nodes: []parse.Node{&parse.ItalicNode{Nodes: []parse.Node{&parse.ItalicNode{Nodes: []parse.Node{textNode("x")}}}}}}),
+			markdown: "*_*x*_*\n",
+		},
+		{
+			name:     "inner emphasis inside a word",
+			document: paragraphOf(&parse.ItalicNode{Nodes: []parse.Node{&parse.ItalicNode{Nodes: []parse.Node{textNode("x")}}, textNode("y")}}),
+			markdown: "_*x*y_\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			generator := NewMarkdownGenerator(test.document)
+			markdown := generator.Markdown()
+			if markdown != test.markdown {
+				t.Errorf("got %q, want %q", markdown, test.markdown)
+			}
+
+			// the markdown parses back to the same tree
+			html := NewGenerator(test.document)
+			if before, after := html.Html(), renderHtml(markdown, 0); before != after {
+				t.Errorf("%q renders\n%s\nwant\n%s", markdown, after, before)
+			}
+		})
+	}
+}
+
+func TestMarkdownEmphasisRoundTrip(t *testing.T) {
+	sources := []string{
+		"*_x_*\n",
+		"_*x*_\n",
+		"***x***\n",
+		"*a _b **c** d_ e*\n",
+		"**a *b* c**\n",
+		"*a*b *c*\n",
+		"foo*bar*baz and _a_ *b*c\n",
+	}
+
+	for _, emphasis := range []byte{'*', '_'} {
+		for _, source := range sources {
+			generator := NewMarkdownGenerator(parse.NewParser(lex.NewLexer(source).Tokenize()).Parse())
+			generator.EmphasisChar = emphasis
+			markdown := generator.Markdown()
+
+			if before, after := renderHtml(source, 0), renderHtml(markdown, 0); before != after {
+				t.Errorf("emphasis %c: %q became %q, which renders\n%s\nwant\n%s", emphasis, source, markdown, after, before)
+			}
+		}
+	}
+}
+
+func TestMarkdownKeepsControlCharacters(t *testing.T) {
+	// these bytes once stood for breakable spaces and hard breaks
+	source := "a\x01b c `x\x01y` <span title=\"\x01\">q</span> d\x00e\nnext\\\nline\n"
+
+	for _, width := range []int{0, 8} {
+		generator := NewMarkdownGenerator(parse.NewParser(lex.NewLexer(source).Tokenize()).Parse())
+		generator.LineWidth = width
+		markdown := generator.Markdown()
+
+		// wrapping turns spaces into soft breaks and back
+		before := strings.Join(strings.Fields(renderHtml(source, 0)), " ")
+		if after := strings.Join(strings.Fields(renderHtml(markdown, 0)), " "); before != after {
+			t.Errorf("width %d: %q renders\n%q\nwant\n%q", width, markdown, after, before)
+		}
+	}
+}
+
+func TestMarkdownWrapping(t *testing.T) {
+	source := "one two three four five six\\\nseven eight nine\n"
+	generator := NewMarkdownGenerator(parse.NewParser(lex.NewLexer(source).Tokenize()).Parse())
+	generator.LineWidth = 10
+
+	want := "one two\nthree four\nfive six\\\nseven\neight nine\n"
+	if markdown := generator.Markdown(); markdown != want {
+		t.Errorf("got %q, want %q", markdown, want)
+	}
+}
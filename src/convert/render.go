@@ -0,0 +1,341 @@
+package gen
+
+import (
+	"allium/src/parse"
+	"fmt"
+	"strings"
+)
+
+// Renderer writes the markup of a single node to the generator.
+type Renderer interface {
+	RenderNode(g *Generator, node parse.Node)
+}
+
+// RenderFunc writes the markup of one kind of node, using the generator's
+// Write, CR, RenderNodes and RenderBlocks to produce output and render
+// children.
+type RenderFunc func(g *Generator, node parse.Node)
+
+// Typed adapts a function taking a concrete node type to a RenderFunc.
+func Typed[T parse.Node](render func(g *Generator, node T)) RenderFunc {
+	return func(g *Generator, node parse.Node) {
+		render(g, node.(T))
+	}
+}
+
+// HtmlRenderer dispatches each node to the RenderFunc registered for its
+// kind. NewHtmlRenderer registers the standard HTML output for every node,
+// and any of them can be replaced with Register:
+//
+//	renderer := gen.NewHtmlRenderer()
+//	image := renderer.Lookup(parse.ImageKind)
+//	renderer.Register(parse.ImageKind, func(g *gen.Generator, node parse.Node) {
+//		g.Write("<figure>")
+//		image(g, node)
+//		g.Write("</figure>")
+//	})
+type HtmlRenderer struct {
+	funcs map[parse.NodeKind]RenderFunc
+}
+
+func NewHtmlRenderer() *HtmlRenderer {
+	var renderer HtmlRenderer
+	renderer.funcs = make(map[parse.NodeKind]RenderFunc)
+
+	renderer.Register(parse.HeaderKind, Typed(renderHeader))
+	renderer.Register(parse.ParagraphKind, Typed(renderParagraph))
+	renderer.Register(parse.ItalicKind, Typed(renderItalic))
+	renderer.Register(parse.BoldKind, Typed(renderBold))
+	renderer.Register(parse.StrikethroughKind, Typed(renderStrikethrough))
+	renderer.Register(parse.TextKind, Typed(renderText))
+	renderer.Register(parse.NewLineKind, Typed(renderNewLine))
+	renderer.Register(parse.LineBreakKind, Typed(renderLineBreak))
+	renderer.Register(parse.LinkKind, Typed(renderLink))
+	renderer.Register(parse.ImageKind, Typed(renderImage))
+	renderer.Register(parse.ListKind, Typed(renderList))
+	renderer.Register(parse.ListItemKind, Typed(renderListItem))
+	renderer.Register(parse.HorizontalRuleKind, Typed(renderHorizontalRule))
+	renderer.Register(parse.BlockQuoteKind, Typed(renderBlockQuote))
+	renderer.Register(parse.InlineCodeKind, Typed(renderInlineCode))
+	renderer.Register(parse.InlineCodeBlockKind, Typed(renderCodeBlock))
+	renderer.Register(parse.HtmlBlockKind, Typed(renderHtmlBlock))
+	renderer.Register(parse.HtmlInlineKind, Typed(renderHtmlInline))
+	renderer.Register(parse.FootnoteReferenceKind, Typed(renderFootnoteReference))
+	renderer.Register(parse.DefinitionListKind, Typed(renderDefinitionList))
+	renderer.Register(parse.TermKind, Typed(renderTerm))
+	renderer.Register(parse.DefinitionKind, Typed(renderDefinition))
+	renderer.Register(parse.TableKind, Typed(renderTable))
+	renderer.Register(parse.TableRowKind, Typed(renderTableRow))
+	renderer.Register(parse.TableCellKind, Typed(renderTableCell))
+
+	return &renderer
+}
+
+// Register sets how nodes of a kind are rendered.
+func (r *HtmlRenderer) Register(kind parse.NodeKind, render RenderFunc) {
+	r.funcs[kind] = render
+}
+
+// Lookup returns the function currently rendering nodes of a kind, or nil
+// if there is none.
+func (r *HtmlRenderer) Lookup(kind parse.NodeKind) RenderFunc {
+	return r.funcs[kind]
+}
+
+func (r *HtmlRenderer) RenderNode(g *Generator, node parse.Node) {
+	render, ok := r.funcs[node.Kind()]
+	if !ok {
+		fmt.Printf("Unknown node type: %T\n", node)
+		return
+	}
+
+	render(g, node)
+}
+
+func renderHeader(g *Generator, node *parse.HeaderNode) {
+	g.HeaderCount++
+
+	g.CR()
+	g.Write("<h%d id=\"%s\">", node.Level, EscapeHtml(g.HeaderId(node)))
+	g.RenderNodes(node.Content)
+	g.Write("</h%d>\n", node.Level)
+}
+
+func renderParagraph(g *Generator, node *parse.ParagraphNode) {
+	if g.TableOfContents && isTocPlaceholder(node) {
+		g.convert_toc()
+		return
+	}
+
+	// paragraphs in tight lists are rendered without their tags
+	if g.InTightList() {
+		g.RenderNodes(node.Content)
+		return
+	}
+
+	g.CR()
+	g.Write("<p>")
+	if g.checkbox != nil {
+		writeCheckbox(g, *g.checkbox)
+		g.checkbox = nil
+	}
+	g.RenderNodes(node.Content)
+	g.Write("</p>\n")
+}
+
+func renderItalic(g *Generator, node *parse.ItalicNode) {
+	g.Write("<em>")
+	g.RenderNodes(node.Nodes)
+	g.Write("</em>")
+}
+
+func renderBold(g *Generator, node *parse.BoldNode) {
+	g.Write("<strong>")
+	g.RenderNodes(node.Nodes)
+	g.Write("</strong>")
+}
+
+func renderStrikethrough(g *Generator, node *parse.StrikethroughNode) {
+	g.Write("<del>")
+	g.RenderNodes(node.Nodes)
+	g.Write("</del>")
+}
+
+func renderText(g *Generator, node *parse.TextNode) {
+	g.Write("%s", EscapeHtml(node.Content))
+}
+
+func renderNewLine(g *Generator, node *parse.NewLineNode) {
+	g.Write("\n")
+}
+
+func renderLineBreak(g *Generator, node *parse.LineBreakNode) {
+	g.Write("<br>\n")
+}
+
+func renderLink(g *Generator, node *parse.LinkNode) {
+	g.Write("<a href=\"%s\"", EscapeHtml(node.Link))
+	if node.Title != "" {
+		g.Write(" title=\"%s\"", EscapeHtml(node.Title))
+	}
+	g.Write(">")
+	g.RenderNodes(node.Nodes)
+	g.Write("</a>")
+}
+
+func renderImage(g *Generator, node *parse.ImageNode) {
+	g.Write("<img src=\"%s\" alt=\"%s\"", EscapeHtml(node.Link), EscapeHtml(node.LinkText))
+	if node.Title != "" {
+		g.Write(" title=\"%s\"", EscapeHtml(node.Title))
+	}
+	g.Write(">")
+}
+
+func renderList(g *Generator, node *parse.ListNode) {
+	tag := "ul"
+	if node.IsOrdered {
+		tag = "ol"
+	}
+
+	g.CR()
+	g.Write("<%s", tag)
+	if node.IsOrdered && node.Start != 1 {
+		g.Write(" start=\"%d\"", node.Start)
+	}
+	if isTaskList(node) {
+		g.Write(" class=\"contains-task-list\"")
+	}
+	g.Write(">\n")
+
+	g.RenderBlocks(node.Nodes, node.IsTight)
+
+	g.CR()
+	g.Write("</%s>\n", tag)
+}
+
+func renderListItem(g *Generator, node *parse.ListItemNode) {
+	g.CR()
+	if node.Checked == nil {
+		g.Write("<li>")
+	} else {
+		g.Write("<li class=\"task-list-item\">")
+
+		// in a loose list the checkbox goes inside the first paragraph
+		if len(node.Nodes) > 0 && !g.InTightList() && isParagraph(g, node.Nodes[0]) {
+			g.checkbox = node.Checked
+		} else {
+			writeCheckbox(g, *node.Checked)
+		}
+	}
+	g.RenderNodes(node.Nodes)
+	g.Write("</li>\n")
+}
+
+func writeCheckbox(g *Generator, checked bool) {
+	g.Write("<input type=\"checkbox\" class=\"task-list-item-checkbox\" disabled")
+	if checked {
+		g.Write(" checked")
+	}
+	g.Write("> ")
+}
+
+// isParagraph reports whether node is rendered as a p element, rather than
+// standing in for the table of contents.
+func isParagraph(g *Generator, node parse.Node) bool {
+	paragraph, ok := node.(*parse.ParagraphNode)
+	return ok && !(g.TableOfContents && isTocPlaceholder(paragraph))
+}
+
+func renderHorizontalRule(g *Generator, node *parse.HorizontalRuleNode) {
+	g.CR()
+	g.Write("<hr>\n")
+}
+
+func renderBlockQuote(g *Generator, node *parse.BlockQuoteNode) {
+	g.CR()
+	g.Write("<blockquote>\n")
+	g.RenderBlocks(node.Nodes, false)
+	g.CR()
+	g.Write("</blockquote>\n")
+}
+
+func renderInlineCode(g *Generator, node *parse.InlineCodeNode) {
+	g.Write("<code>%s</code>", EscapeHtml(node.Content))
+}
+
+func renderCodeBlock(g *Generator, node *parse.InlineCodeBlockNode) {
+	language, _, _ := strings.Cut(node.Info, " ")
+
+	g.CR()
+	g.Write("<pre><code")
+	if language != "" {
+		g.Write(" class=\"language-%s\"", EscapeHtml(language))
+	}
+	g.Write(">")
+	if highlighted, ok := g.highlight(node.Content, language); ok {
+		g.Write("%s", highlighted)
+	} else {
+		g.Write("%s", EscapeHtml(node.Content))
+	}
+	g.Write("</code></pre>\n")
+}
+
+func renderHtmlBlock(g *Generator, node *parse.HtmlBlockNode) {
+	g.CR()
+	g.Write("%s\n", node.Content)
+}
+
+func renderHtmlInline(g *Generator, node *parse.HtmlInlineNode) {
+	g.Write("%s", node.Content)
+}
+
+func renderFootnoteReference(g *Generator, node *parse.FootnoteReferenceNode) {
+	g.Write("<sup class=\"footnote-ref\"><a href=\"#fn-%d\" id=\"%s\">%d</a></sup>", node.Index, footnoteReferenceId(node.Index, node.Occurrence), node.Index)
+}
+
+func renderDefinitionList(g *Generator, node *parse.DefinitionListNode) {
+	g.CR()
+	g.Write("<dl>\n")
+	g.RenderNodes(node.Nodes)
+	g.CR()
+	g.Write("</dl>\n")
+}
+
+func renderTerm(g *Generator, node *parse.TermNode) {
+	g.CR()
+	g.Write("<dt>")
+	g.RenderNodes(node.Content)
+	g.Write("</dt>\n")
+}
+
+func renderDefinition(g *Generator, node *parse.DefinitionNode) {
+	g.CR()
+	g.Write("<dd>")
+	g.RenderBlocks(node.Nodes, node.IsTight)
+	g.Write("</dd>\n")
+}
+
+func renderTable(g *Generator, node *parse.TableNode) {
+	g.CR()
+	g.Write("<table>\n")
+	for i, row := range node.Nodes {
+		if i == 0 {
+			g.Write("<thead>\n")
+		} else if i == 1 {
+			g.Write("<tbody>\n")
+		}
+
+		g.RenderNode(row)
+
+		if i == 0 {
+			g.Write("</thead>\n")
+		}
+	}
+	if len(node.Nodes) > 1 {
+		g.Write("</tbody>\n")
+	}
+	g.Write("</table>\n")
+}
+
+func renderTableRow(g *Generator, node *parse.TableRowNode) {
+	g.Write("<tr>\n")
+	g.RenderNodes(node.Nodes)
+	g.Write("</tr>\n")
+}
+
+func renderTableCell(g *Generator, node *parse.TableCellNode) {
+	tag := "td"
+	if node.IsHeader {
+		tag = "th"
+	}
+
+	g.Write("<%s", tag)
+	if node.Alignment != "" && g.TableStyleAlignment {
+		g.Write(" style=\"text-align: %s\"", node.Alignment)
+	} else if node.Alignment != "" {
+		g.Write(" align=\"%s\"", node.Alignment)
+	}
+	g.Write(">")
+	g.RenderNodes(node.Content)
+	g.Write("</%s>\n", tag)
+}
@@ -0,0 +1,130 @@
+package gen
+
+import (
+	"allium/src/lex"
+	"allium/src/parse"
+	"strings"
+	"testing"
+)
+
+func renderHtml(source string, extensions parse.Extension) string {
+	parser := parse.NewParser(lex.NewLexer(source).Tokenize())
+	parser.Extensions = extensions
+	generator := NewGenerator(parser.Parse())
+
+	return generator.Html()
+}
+
+func TestRenderTaskList(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		html     string
+	}{
+		{
+			name:     "tight",
+			markdown: "- [x] a\n- [ ] b\n",
+			html: "<ul class=\"contains-task-list\">\n" +
+				"<li class=\"task-list-item\"><input type=\"checkbox\" class=\"task-list-item-checkbox\" disabled checked> a</li>\n" +
+				"<li class=\"task-list-item\"><input type=\"checkbox\" class=\"task-list-item-checkbox\" disabled> b</li>\n" +
+				"</ul>\n",
+		},
+		{
+			name:     "loose",
+			markdown: "- [x] a\n\n- [ ] b\n",
+			html: "<ul class=\"contains-task-list\">\n" +
+				"<li class=\"task-list-item\">\n<p><input type=\"checkbox\" class=\"task-list-item-checkbox\" disabled checked> a</p>\n</li>\n" +
+				"<li class=\"task-list-item\">\n<p><input type=\"checkbox\" class=\"task-list-item-checkbox\" disabled> b</p>\n</li>\n" +
+				"</ul>\n",
+		},
+	}
+
+	for _, test := range tests {
+		if html := renderHtml(test.markdown, parse.TaskListExtension); html != test.html {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.name, html, test.html)
+		}
+	}
+}
+
+func renderWith(source string, renderer Renderer) string {
+	parser := parse.NewParser(lex.NewLexer(source).Tokenize())
+	generator := NewGenerator(parser.Parse())
+	generator.Renderer = renderer
+
+	return generator.Html()
+}
+
+func TestRegisterOverridesDefault(t *testing.T) {
+	source := "![a](b.png) and *c*\n"
+	renderer := NewHtmlRenderer()
+	image := renderer.Lookup(parse.ImageKind)
+
+	// the replacement falls back to the default it looked up
+	renderer.Register(parse.ImageKind, func(g *Generator, node parse.Node) {
+		g.Write("<figure>")
+		image(g, node)
+		g.Write("</figure>")
+	})
+	want := "<p><figure><img src=\"b.png\" alt=\"a\"></figure> and <em>c</em></p>\n"
+	if html := renderWith(source, renderer); html != want {
+		t.Errorf("with the replacement got %q, want %q", html, want)
+	}
+
+	// registering the default again undoes the replacement
+	renderer.Register(parse.ImageKind, image)
+	want = renderHtml(source, 0)
+	if html := renderWith(source, renderer); html != want {
+		t.Errorf("with the default again got %q, want %q", html, want)
+	}
+}
+
+func TestRegisterReplacesDefault(t *testing.T) {
+	renderer := NewHtmlRenderer()
+	renderer.Register(parse.ItalicKind, Typed(func(g *Generator, node *parse.ItalicNode) {
+		g.Write("<i>")
+		g.RenderNodes(node.Nodes)
+		g.Write("</i>")
+	}))
+
+	// nested nodes go back through the renderer, other kinds are unchanged
+	want := "<p><i>a <strong>b</strong> c</i> and <strong><i>d</i></strong></p>\n"
+	if html := renderWith("*a **b** c* and **_d_**\n", renderer); html != want {
+		t.Errorf("got %q, want %q", html, want)
+	}
+
+	// renderers are independent of each other
+	if html := renderHtml("*a*\n", 0); html != "<p><em>a</em></p>\n" {
+		t.Errorf("a new generator got %q", html)
+	}
+}
+
+// rawText renders text nodes itself and everything else with an
+// HtmlRenderer, as a Renderer not built on Register would.
+type rawText struct {
+	fallback *HtmlRenderer
+}
+
+func (r rawText) RenderNode(g *Generator, node parse.Node) {
+	if text, ok := node.(*parse.TextNode); ok {
+		g.Write("%s", EscapeHtml(strings.ToUpper(text.Content)))
+		return
+	}
+	r.fallback.RenderNode(g, node)
+}
+
+func TestRendererFallsBack(t *testing.T) {
+	want := "<h1 id=\"a\">A</h1>\n<ul>\n<li>B <code>c</code></li>\n</ul>\n"
+	if html := renderWith("# a\n\n- b `c`\n", rawText{NewHtmlRenderer()}); html != want {
+		t.Errorf("got %q, want %q", html, want)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	renderer := NewHtmlRenderer()
+	if renderer.Lookup(parse.ParagraphKind) == nil {
+		t.Error("no default for paragraphs")
+	}
+	if renderer.Lookup(parse.DocumentKind) != nil {
+		t.Error("documents have a render function")
+	}
+}
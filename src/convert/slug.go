@@ -0,0 +1,74 @@
+package gen
+
+import (
+	"allium/src/parse"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// SlugFunc turns the plain text of a heading into an anchor ID.
+type SlugFunc func(text string) string
+
+// GitHubSlug lowercases the text, drops everything but letters, digits,
+// spaces, hyphens and underscores, and turns each space into a hyphen, the
+// same way GitHub anchors headings.
+func GitHubSlug(text string) string {
+	var slug strings.Builder
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case r == ' ':
+			slug.WriteRune('-')
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r):
+			slug.WriteRune(r)
+		}
+	}
+
+	return slug.String()
+}
+
+// assignHeaderIds gives every heading in the document a unique ID. Explicit
+// IDs are reserved before any slugs are generated, so they keep their ID
+// unless an earlier heading asked for the same one. Everything else gets a
+// "-1", "-2" suffix when its ID is already taken.
+func (g *Generator) assignHeaderIds() {
+	g.headerIds = make(map[*parse.HeaderNode]string)
+	occurrences := make(map[string]int)
+	headers := parse.FindAll(g.Document, parse.HeaderKind)
+
+	for _, node := range headers {
+		header := node.(*parse.HeaderNode)
+		if _, taken := occurrences[header.ID]; header.ID != "" && !taken {
+			g.headerIds[header] = header.ID
+			occurrences[header.ID] = 0
+		}
+	}
+
+	for _, node := range headers {
+		header := node.(*parse.HeaderNode)
+		if _, ok := g.headerIds[header]; ok {
+			continue
+		}
+
+		slug := header.ID
+		if slug == "" {
+			slug = g.Slugify(parse.PlainText(header.Content))
+		}
+		if slug == "" {
+			slug = "header"
+		}
+
+		id := slug
+		for {
+			if _, taken := occurrences[id]; !taken {
+				break
+			}
+			occurrences[slug]++
+			id = fmt.Sprintf("%s-%d", slug, occurrences[slug])
+		}
+		occurrences[id] = 0
+
+		g.headerIds[header] = id
+	}
+}
@@ -0,0 +1,69 @@
+package gen
+
+import (
+	"allium/src/lex"
+	"allium/src/parse"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var idAttribute = regexp.MustCompile(`<h[1-6] id="([^"]*)"`)
+
+func headerIds(source string, slugify SlugFunc) []string {
+	parser := parse.NewParser(lex.NewLexer(source).Tokenize())
+	parser.Extensions = parse.HeaderIdsExtension
+	generator := NewGenerator(parser.Parse())
+	if slugify != nil {
+		generator.Slugify = slugify
+	}
+
+	var ids []string
+	for _, match := range idAttribute.FindAllStringSubmatch(generator.Html(), -1) {
+		ids = append(ids, match[1])
+	}
+
+	return ids
+}
+
+func TestGitHubSlug(t *testing.T) {
+	tests := map[string]string{
+		"Hello World":         "hello-world",
+		"What's new?":         "whats-new",
+		"snake_case & kebab-": "snake_case--kebab-",
+		"Ünïcödé 2":           "ünïcödé-2",
+	}
+
+	for text, slug := range tests {
+		if got := GitHubSlug(text); got != slug {
+			t.Errorf("GitHubSlug(%q) = %q, want %q", text, got, slug)
+		}
+	}
+}
+
+func TestAssignHeaderIds(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		ids      []string
+	}{
+		{"repeated slugs", "# A\n# A\n# A\n", []string{"a", "a-1", "a-2"}},
+		{"empty heading", "#\n# !\n", []string{"header", "header-1"}},
+		{"explicit ID", "# A {#custom}\n## A\n", []string{"custom", "a"}},
+		{"explicit ID after its slug", "# my-id\n# A {#my-id}\n", []string{"my-id-1", "my-id"}},
+		{"repeated explicit ID", "# A {#x}\n# B {#x}\n", []string{"x", "x-1"}},
+		{"explicit ID with a suffix", "# A\n# A\n# B {#a-1}\n", []string{"a", "a-2", "a-1"}},
+		{"nested heading", "> # A\n\n- # A\n", []string{"a", "a-1"}},
+	}
+
+	for _, test := range tests {
+		if ids := headerIds(test.markdown, nil); !slices.Equal(ids, test.ids) {
+			t.Errorf("%s: got %q, want %q", test.name, ids, test.ids)
+		}
+	}
+
+	if ids := headerIds("# A b\n# A b\n", strings.ToUpper); !slices.Equal(ids, []string{"A B", "A B-1"}) {
+		t.Errorf("custom slug function: got %q", ids)
+	}
+}
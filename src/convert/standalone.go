@@ -0,0 +1,79 @@
+package gen
+
+import (
+	"allium/src/parse"
+	_ "embed"
+	"html/template"
+	"strings"
+)
+
+//go:embed templates/standalone.html
+var standaloneTemplate string
+
+// DefaultTemplate wraps the body in a minimal HTML5 document declaring a
+// UTF-8 charset, the title, description, author and any stylesheets.
+var DefaultTemplate = template.Must(template.New("standalone").Parse(standaloneTemplate))
+
+// TemplateData is what a standalone template is executed with. Metadata
+// holds the front matter fields, if there are any, and Style the inlined
+// theme.
+type TemplateData struct {
+	Title       string
+	Stylesheets []string
+	Style       template.CSS
+	Metadata    map[string]any
+	Body        template.HTML
+}
+
+// HtmlDocument renders the document and executes the template around it,
+// using DefaultTemplate when no Template is set.
+func (g *Generator) HtmlDocument() (string, error) {
+	var data TemplateData
+	data.Title = g.documentTitle()
+	data.Stylesheets = g.Stylesheets
+	if g.Theme != "" && g.LinkTheme {
+		data.Stylesheets = append([]string{themeFileName(g.Theme)}, g.Stylesheets...)
+	} else if g.Theme != "" {
+		css, err := ThemeCSS(g.Theme)
+		if err != nil {
+			return "", err
+		}
+		data.Style = template.CSS(css)
+	}
+	if g.Highlight != nil {
+		data.Style += template.CSS(HighlightCSS)
+	}
+	data.Metadata = make(map[string]any)
+	if g.Document.FrontMatter != nil {
+		data.Metadata = g.Document.FrontMatter.Fields
+	}
+	data.Body = template.HTML(g.Html())
+
+	tmpl := g.Template
+	if tmpl == nil {
+		tmpl = DefaultTemplate
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+
+	return out.String(), nil
+}
+
+// documentTitle prefers the front matter title, falling back to the text of
+// the first top level heading.
+func (g *Generator) documentTitle() string {
+	if title := g.Title(); title != "" {
+		return title
+	}
+
+	for _, node := range g.Document.Nodes {
+		if header, ok := node.(*parse.HeaderNode); ok {
+			return parse.PlainText(header.Content)
+		}
+	}
+
+	return ""
+}
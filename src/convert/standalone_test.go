@@ -0,0 +1,130 @@
+package gen
+
+import (
+	"allium/src/lex"
+	"allium/src/parse"
+	"html/template"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func standaloneGenerator(source string) Generator {
+	parser := parse.NewParser(lex.NewLexer(source).Tokenize())
+	parser.Extensions = parse.FrontMatterExtension
+	generator := NewGenerator(parser.Parse())
+	generator.Standalone = true
+
+	return generator
+}
+
+func TestHtmlDocument(t *testing.T) {
+	tests := []struct {
+		name        string
+		markdown    string
+		stylesheets []string
+		html        string
+	}{
+		{
+			name:     "title from the first heading",
+			markdown: "text\n\n# Hello *world*\n\n# Other\n",
+			html: "<!DOCTYPE html>\n" +
+				"<html>\n" +
+				"<head>\n" +
+				"<meta charset=\"utf-8\">\n" +
+				"<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n" +
+				"<title>Hello world</title>\n" +
+				"</head>\n" +
+				"<body>\n" +
+				"<p>text</p>\n" +
+				"<h1 id=\"hello-world\">Hello <em>world</em></h1>\n" +
+				"<h1 id=\"other\">Other</h1>\n" +
+				"</body>\n" +
+				"</html>\n",
+		},
+		{
+			name:     "no title",
+			markdown: "> # quoted\n",
+			html: "<!DOCTYPE html>\n" +
+				"<html>\n" +
+				"<head>\n" +
+				"<meta charset=\"utf-8\">\n" +
+				"<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n" +
+				"<title></title>\n" +
+				"</head>\n" +
+				"<body>\n" +
+				"<blockquote>\n<h1 id=\"quoted\">quoted</h1>\n</blockquote>\n" +
+				"</body>\n" +
+				"</html>\n",
+		},
+		{
+			name:        "front matter and stylesheets",
+			markdown:    "---\ntitle: A <b> & \"c\"\nlang: fr\ndescription: x\"y\nauthor: Me & You\n---\n# Other\n",
+			stylesheets: []string{"a.css", "b c.css?x=1&y=2"},
+			html: "<!DOCTYPE html>\n" +
+				"<html lang=\"fr\">\n" +
+				"<head>\n" +
+				"<meta charset=\"utf-8\">\n" +
+				"<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n" +
+				"<title>A &lt;b&gt; &amp; &#34;c&#34;</title>\n" +
+				"<meta name=\"description\" content=\"x&#34;y\">\n" +
+				"<meta name=\"author\" content=\"Me &amp; You\">\n" +
+				"<link rel=\"stylesheet\" href=\"a.css\">\n" +
+				"<link rel=\"stylesheet\" href=\"b%20c.css?x=1&amp;y=2\">\n" +
+				"</head>\n" +
+				"<body>\n" +
+				"<h1 id=\"other\">Other</h1>\n" +
+				"</body>\n" +
+				"</html>\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			generator := standaloneGenerator(test.markdown)
+			generator.Stylesheets = test.stylesheets
+			html, err := generator.HtmlDocument()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if html != test.html {
+				t.Errorf("got\n%s\nwant\n%s", html, test.html)
+			}
+		})
+	}
+}
+
+func TestHtmlDocumentTemplate(t *testing.T) {
+	generator := standaloneGenerator("---\ntitle: T\ntags: [a, b]\n---\n*x*\n")
+	generator.Template = template.Must(template.New("page").Parse(
+		"<main title=\"{{.Title}}\">{{range .Metadata.tags}}[{{.}}]{{end}}\n{{.Body}}</main>\n"))
+
+	html, err := generator.HtmlDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<main title=\"T\">[a][b]\n<p><em>x</em></p>\n</main>\n"; html != want {
+		t.Errorf("got %q, want %q", html, want)
+	}
+
+	generator.Template = template.Must(template.New("page").Parse("{{template \"missing\"}}"))
+	if _, err := generator.HtmlDocument(); err == nil {
+		t.Error("a failing template gave no error")
+	}
+}
+
+func TestGenerateHtmlStandalone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.html")
+	generator := standaloneGenerator("# a\n")
+	if err := generator.GenerateHtml(path); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := generator.HtmlDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != want {
+		t.Errorf("wrote\n%s\nwant\n%s", data, want)
+	}
+}
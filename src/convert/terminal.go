@@ -0,0 +1,620 @@
+package gen
+
+import (
+	"allium/src/parse"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// ANSI escape sequences used by the terminal generator.
+const (
+	ansiReset     = "\x1b[0m"
+	ansiBold      = "\x1b[1m"
+	ansiDim       = "\x1b[2m"
+	ansiItalic    = "\x1b[3m"
+	ansiUnderline = "\x1b[4m"
+	ansiStrike    = "\x1b[9m"
+	ansiYellow    = "\x1b[33m"
+	ansiBlue      = "\x1b[34m"
+	ansiMagenta   = "\x1b[35m"
+	ansiCyan      = "\x1b[36m"
+)
+
+// TerminalGenerator renders a document as styled text for a terminal, with
+// ANSI escape sequences for emphasis and colour, boxed code blocks and
+// tables, and links written as OSC 8 hyperlinks.
+type TerminalGenerator struct {
+	Document *parse.DocumentNode
+
+	// Width wraps paragraphs at this many columns, including the
+	// indentation of their containers. Zero leaves lines unwrapped.
+	Width int
+
+	// Hyperlinks makes link text clickable in terminals that support OSC 8
+	// hyperlinks. Without it the URL follows the link text.
+	Hyperlinks bool
+}
+
+func NewTerminalGenerator(document *parse.DocumentNode) TerminalGenerator {
+	var gen = TerminalGenerator{}
+	gen.Document = document
+	gen.Width = 80
+	gen.Hyperlinks = true
+
+	return gen
+}
+
+func (g *TerminalGenerator) GenerateTerminal(outputPath string) error {
+	return os.WriteFile(outputPath, []byte(g.Terminal()), 0644)
+}
+
+// Terminal renders the document and returns the resulting text.
+func (g *TerminalGenerator) Terminal() string {
+	var sections []string
+
+	if lines := g.convert_blocks(g.Document.Nodes, false, g.Width); len(lines) > 0 {
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+
+	for _, footnote := range g.Document.Footnotes {
+		if footnote.Index == 0 {
+			continue
+		}
+		label := fmt.Sprintf("[%d] ", footnote.Index)
+		lines := g.convert_blocks(footnote.Nodes, false, narrow(g.Width, len(label)))
+		sections = append(sections, strings.Join(prefixLines(lines, styled(ansiCyan, label), strings.Repeat(" ", len(label))), "\n"))
+	}
+
+	if len(sections) == 0 {
+		return ""
+	}
+
+	return strings.Join(sections, "\n\n") + "\n"
+}
+
+func (g *TerminalGenerator) convert_blocks(nodes []parse.Node, tight bool, width int) []string {
+	var lines []string
+
+	for _, node := range nodes {
+		block := g.convert_block(node, width)
+		if len(block) == 0 {
+			continue
+		}
+
+		if len(lines) > 0 && !tight {
+			lines = append(lines, "")
+		}
+		lines = append(lines, block...)
+	}
+
+	return lines
+}
+
+func (g *TerminalGenerator) convert_block(node parse.Node, width int) []string {
+	switch node := node.(type) {
+	case *parse.ParagraphNode:
+		return g.convert_inlines(node.Content, "").wrap(width)
+	case *parse.HeaderNode:
+		return g.convert_inlines(node.Content, headerStyle(node.Level)).wrap(width)
+	case *parse.HorizontalRuleNode:
+		return []string{styled(ansiDim, strings.Repeat("─", ruleWidth(width)))}
+	case *parse.InlineCodeBlockNode:
+		return convert_code_box(node, width)
+	case *parse.HtmlBlockNode:
+		text := strings.TrimSpace(stripTags(node.Content))
+		if text == "" {
+			return nil
+		}
+		var lines []string
+		for _, line := range strings.Split(text, "\n") {
+			lines = append(lines, styled(ansiDim, escapeControls(line)))
+		}
+		return lines
+	case *parse.BlockQuoteNode:
+		bar := styled(ansiDim, "│") + " "
+		return prefixLines(g.convert_blocks(node.Nodes, false, narrow(width, 2)), bar, bar)
+	case *parse.ListNode:
+		return g.convert_list(node, width)
+	case *parse.TableNode:
+		return g.convert_table(node)
+	case *parse.DefinitionListNode:
+		return g.convert_definition_list(node, width)
+	}
+
+	return nil
+}
+
+func headerStyle(level int) string {
+	switch level {
+	case 1:
+		return ansiBold + ansiUnderline + ansiMagenta
+	case 2:
+		return ansiBold + ansiMagenta
+	}
+	return ansiBold
+}
+
+func ruleWidth(width int) int {
+	if width <= 0 {
+		return 40
+	}
+	return width
+}
+
+// convert_code_box draws a box around a code block, naming its language in
+// the top border. Code too wide for the line width is left to run on past
+// a box that is open on the right.
+func convert_code_box(code *parse.InlineCodeBlockNode, width int) []string {
+	var lines []string
+	if code.Content != "" {
+		content := escapeControls(strings.ReplaceAll(code.Content, "\t", "    "))
+		lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+
+	language, _, _ := strings.Cut(escapeControls(code.Info), " ")
+	inner := displayWidth(language) + 3
+	for _, line := range lines {
+		inner = max(inner, displayWidth(line)+2)
+	}
+
+	open := width > 0 && inner+2 > width
+	left, right, corners := "│", "│", [4]string{"┌", "┐", "└", "┘"}
+	if open {
+		inner = max(width-1, displayWidth(language)+3)
+		right, corners[1], corners[3] = "", "", ""
+	}
+
+	top := corners[0] + strings.Repeat("─", inner) + corners[1]
+	if language != "" {
+		top = corners[0] + "─ " + language + " " + strings.Repeat("─", inner-displayWidth(language)-3) + corners[1]
+	}
+
+	box := []string{styled(ansiDim, top)}
+	for _, line := range lines {
+		if open {
+			box = append(box, styled(ansiDim, left)+" "+styled(ansiYellow, line))
+			continue
+		}
+		padding := strings.Repeat(" ", inner-displayWidth(line)-2)
+		box = append(box, styled(ansiDim, left)+" "+styled(ansiYellow, line)+padding+" "+styled(ansiDim, right))
+	}
+
+	return append(box, styled(ansiDim, corners[2]+strings.Repeat("─", inner)+corners[3]))
+}
+
+func (g *TerminalGenerator) convert_list(list *parse.ListNode, width int) []string {
+	var lines []string
+
+	for i, node := range list.Nodes {
+		item, ok := node.(*parse.ListItemNode)
+		if !ok {
+			continue
+		}
+
+		marker := "•"
+		if list.IsOrdered {
+			marker = fmt.Sprintf("%d.", list.Start+i)
+		}
+		if item.Checked != nil {
+			if *item.Checked {
+				marker += " [x]"
+			} else {
+				marker += " [ ]"
+			}
+		}
+		indent := strings.Repeat(" ", displayWidth(marker)+1)
+
+		if i > 0 && !list.IsTight {
+			lines = append(lines, "")
+		}
+
+		content := g.convert_blocks(item.Nodes, list.IsTight, narrow(width, len(indent)))
+		if len(content) == 0 {
+			lines = append(lines, styled(ansiCyan, marker))
+			continue
+		}
+		lines = append(lines, prefixLines(content, styled(ansiCyan, marker)+" ", indent)...)
+	}
+
+	return lines
+}
+
+func (g *TerminalGenerator) convert_definition_list(list *parse.DefinitionListNode, width int) []string {
+	var lines []string
+	var previous parse.Node
+
+	for _, node := range list.Nodes {
+		switch node := node.(type) {
+		case *parse.TermNode:
+			if _, ok := previous.(*parse.DefinitionNode); ok {
+				lines = append(lines, "")
+			}
+			lines = append(lines, g.convert_inlines(node.Content, ansiBold).wrap(width)...)
+		case *parse.DefinitionNode:
+			if !node.IsTight {
+				lines = append(lines, "")
+			}
+			lines = append(lines, prefixLines(g.convert_blocks(node.Nodes, node.IsTight, narrow(width, 4)), "    ", "    ")...)
+		}
+		previous = node
+	}
+
+	return lines
+}
+
+// convert_table draws a table in a box, with a rule under the header row.
+func (g *TerminalGenerator) convert_table(table *parse.TableNode) []string {
+	type cell struct {
+		text      string
+		width     int
+		alignment string
+	}
+
+	var rows [][]cell
+	var widths []int
+	for _, node := range table.Nodes {
+		row, ok := node.(*parse.TableRowNode)
+		if !ok {
+			continue
+		}
+
+		var cells []cell
+		for i, node := range row.Nodes {
+			tableCell, ok := node.(*parse.TableCellNode)
+			if !ok {
+				continue
+			}
+
+			style := ""
+			if tableCell.IsHeader {
+				style = ansiBold
+			}
+			text := g.convert_inlines(tableCell.Content, style)
+			cells = append(cells, cell{text: text.line(), width: text.width(), alignment: tableCell.Alignment})
+
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], text.width())
+		}
+		rows = append(rows, cells)
+	}
+
+	border := func(left string, middle string, right string) string {
+		var line strings.Builder
+		line.WriteString(left)
+		for i, width := range widths {
+			if i > 0 {
+				line.WriteString(middle)
+			}
+			line.WriteString(strings.Repeat("─", width+2))
+		}
+		line.WriteString(right)
+		return styled(ansiDim, line.String())
+	}
+
+	lines := []string{border("┌", "┬", "┐")}
+	for r, row := range rows {
+		var line strings.Builder
+		line.WriteString(styled(ansiDim, "│"))
+		for i, width := range widths {
+			var c cell
+			if i < len(row) {
+				c = row[i]
+			}
+
+			padding := width - c.width
+			switch c.alignment {
+			case "right":
+				line.WriteString(" " + strings.Repeat(" ", padding) + c.text + " ")
+			case "center":
+				line.WriteString(" " + strings.Repeat(" ", padding/2) + c.text + strings.Repeat(" ", padding-padding/2) + " ")
+			default:
+				line.WriteString(" " + c.text + strings.Repeat(" ", padding) + " ")
+			}
+			line.WriteString(styled(ansiDim, "│"))
+		}
+		lines = append(lines, line.String())
+
+		if r == 0 && len(rows) > 1 {
+			lines = append(lines, border("├", "┼", "┤"))
+		}
+	}
+
+	return append(lines, border("└", "┴", "┘"))
+}
+
+// convert_inlines renders inline nodes as words, each styled on its own so
+// that lines can be wrapped and indented without styles running over.
+func (g *TerminalGenerator) convert_inlines(nodes []parse.Node, style string) *terminalText {
+	var text terminalText
+	g.write_inlines(&text, nodes, style, "")
+	text.endWord()
+
+	return &text
+}
+
+func (g *TerminalGenerator) write_inlines(text *terminalText, nodes []parse.Node, style string, link string) {
+	for _, node := range nodes {
+		switch node := node.(type) {
+		case *parse.TextNode:
+			text.write(node.Content, style, link)
+		case *parse.InlineCodeNode:
+			text.write(node.Content, style+ansiYellow, link)
+		case *parse.ItalicNode:
+			g.write_inlines(text, node.Nodes, style+ansiItalic, link)
+		case *parse.BoldNode:
+			g.write_inlines(text, node.Nodes, style+ansiBold, link)
+		case *parse.StrikethroughNode:
+			g.write_inlines(text, node.Nodes, style+ansiStrike, link)
+		case *parse.LinkNode:
+			if g.Hyperlinks {
+				g.write_inlines(text, node.Nodes, style+ansiUnderline+ansiBlue, node.Link)
+				break
+			}
+			g.write_inlines(text, node.Nodes, style+ansiUnderline+ansiBlue, "")
+			if node.Link != "" && parse.PlainText(node.Nodes) != node.Link {
+				text.write(" ("+node.Link+")", style+ansiDim, "")
+			}
+		case *parse.ImageNode:
+			alt := "image"
+			if node.LinkText != "" {
+				alt = "image: " + node.LinkText
+			}
+			imageLink := ""
+			if g.Hyperlinks {
+				imageLink = node.Link
+			}
+			text.write("["+alt+"]", style+ansiCyan, imageLink)
+			if !g.Hyperlinks && node.Link != "" {
+				text.write(" ("+node.Link+")", style+ansiDim, "")
+			}
+		case *parse.HtmlInlineNode:
+			text.write(stripTags(node.Content), style, link)
+		case *parse.FootnoteReferenceNode:
+			text.write(fmt.Sprintf("[%d]", node.Index), style+ansiCyan, link)
+		case *parse.NewLineNode:
+			text.write(" ", style, link)
+		case *parse.LineBreakNode:
+			text.lineBreak()
+		}
+	}
+}
+
+// terminalText is inline content broken into words, with the styles and
+// hyperlinks already applied to each piece of a word.
+type terminalText struct {
+	words []terminalWord
+	word  terminalWord
+}
+
+// terminalWord is a word of styled text. A word with lineBreak set marks a
+// hard line break instead. The style and link it starts and ends with let
+// the space between two words carry on an underline or a hyperlink.
+type terminalWord struct {
+	text      string
+	width     int
+	lineBreak bool
+
+	firstStyle, firstLink string
+	lastStyle, lastLink   string
+}
+
+// space returns the space to put between two words on a line.
+func space(before terminalWord, after terminalWord) string {
+	if before.lastStyle != after.firstStyle || before.lastLink != after.firstLink {
+		return " "
+	}
+
+	return hyperlink(before.lastLink, styled(before.lastStyle, " "))
+}
+
+// write adds text in a style, ending the current word at each space.
+// Control characters are made visible rather than sent to the terminal.
+func (t *terminalText) write(s string, style string, link string) {
+	for i, piece := range strings.Split(escapeControls(s), " ") {
+		if i > 0 {
+			t.endWord()
+		}
+		if piece == "" {
+			continue
+		}
+
+		if t.word.text == "" {
+			t.word.firstStyle, t.word.firstLink = style, link
+		}
+		t.word.lastStyle, t.word.lastLink = style, link
+		t.word.text += hyperlink(link, styled(style, piece))
+		t.word.width += displayWidth(piece)
+	}
+}
+
+func (t *terminalText) endWord() {
+	if t.word.text != "" {
+		t.words = append(t.words, t.word)
+	}
+	t.word = terminalWord{}
+}
+
+func (t *terminalText) lineBreak() {
+	t.endWord()
+	t.words = append(t.words, terminalWord{lineBreak: true})
+}
+
+// wrap fills lines with words up to width columns, or puts every word on
+// one line when width is zero. Hard breaks always start a new line.
+func (t *terminalText) wrap(width int) []string {
+	var lines []string
+	var line strings.Builder
+	var previous terminalWord
+	lineWidth := 0
+
+	for _, word := range t.words {
+		if word.lineBreak {
+			lines = append(lines, line.String())
+			line.Reset()
+			lineWidth = 0
+			continue
+		}
+
+		if lineWidth > 0 && width > 0 && lineWidth+1+word.width > width {
+			lines = append(lines, line.String())
+			line.Reset()
+			lineWidth = 0
+		}
+		if lineWidth > 0 {
+			line.WriteString(space(previous, word))
+			lineWidth++
+		}
+		line.WriteString(word.text)
+		lineWidth += word.width
+		previous = word
+	}
+
+	return append(lines, line.String())
+}
+
+// line joins every word on a single line.
+func (t *terminalText) line() string {
+	return strings.Join(t.wrap(0), " ")
+}
+
+// width is the number of columns the text takes up on a single line.
+func (t *terminalText) width() int {
+	width := 0
+	for i, word := range t.words {
+		if i > 0 {
+			width++
+		}
+		width += word.width
+	}
+
+	return width
+}
+
+func styled(style string, text string) string {
+	if style == "" {
+		return text
+	}
+
+	return style + text + ansiReset
+}
+
+// hyperlink wraps text in an OSC 8 hyperlink to link, when there is one.
+// Control characters in the link are percent-encoded so that it cannot end
+// the escape sequence early.
+func hyperlink(link string, text string) string {
+	if link == "" {
+		return text
+	}
+
+	var escaped strings.Builder
+	for _, r := range link {
+		if isControl(r) {
+			fmt.Fprintf(&escaped, "%%%02X", r)
+		} else {
+			escaped.WriteRune(r)
+		}
+	}
+
+	return "\x1b]8;;" + escaped.String() + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
+
+// isControl reports whether a terminal could act on r rather than show it,
+// which is true of the C0 and C1 control characters and DEL.
+func isControl(r rune) bool {
+	return r < 0x20 || (r >= 0x7f && r < 0xa0)
+}
+
+// escapeControls replaces the control characters in s, other than tabs and
+// newlines, with the symbols of the Control Pictures block, so "\x1b" shows
+// as "␛". C1 controls have no symbol and become "�".
+func escapeControls(s string) string {
+	if strings.IndexFunc(s, func(r rune) bool { return isControl(r) && r != '\t' && r != '\n' }) < 0 {
+		return s
+	}
+
+	var escaped strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\t' || r == '\n' || !isControl(r):
+			escaped.WriteRune(r)
+		case r < 0x20:
+			escaped.WriteRune(0x2400 + r)
+		case r == 0x7f:
+			escaped.WriteRune('␡')
+		default:
+			escaped.WriteRune(unicode.ReplacementChar)
+		}
+	}
+
+	return escaped.String()
+}
+
+// wideRanges are the characters that take up two columns in a terminal:
+// the East Asian wide and fullwidth ones and emoji.
+var wideRanges = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x1100, Hi: 0x115f, Stride: 1},
+		{Lo: 0x231a, Hi: 0x231b, Stride: 1},
+		{Lo: 0x23e9, Hi: 0x23ec, Stride: 1},
+		{Lo: 0x25fd, Hi: 0x25fe, Stride: 1},
+		{Lo: 0x2614, Hi: 0x2615, Stride: 1},
+		{Lo: 0x26aa, Hi: 0x26ab, Stride: 1},
+		{Lo: 0x26bd, Hi: 0x26be, Stride: 1},
+		{Lo: 0x26f5, Hi: 0x26fa, Stride: 5},
+		{Lo: 0x2705, Hi: 0x2705, Stride: 1},
+		{Lo: 0x270a, Hi: 0x270b, Stride: 1},
+		{Lo: 0x274c, Hi: 0x274c, Stride: 1},
+		{Lo: 0x2753, Hi: 0x2755, Stride: 1},
+		{Lo: 0x2795, Hi: 0x2797, Stride: 1},
+		{Lo: 0x2b1b, Hi: 0x2b1c, Stride: 1},
+		{Lo: 0x2b50, Hi: 0x2b55, Stride: 5},
+		{Lo: 0x2e80, Hi: 0x303e, Stride: 1},
+		{Lo: 0x3041, Hi: 0x33ff, Stride: 1},
+		{Lo: 0x3400, Hi: 0x4dbf, Stride: 1},
+		{Lo: 0x4e00, Hi: 0x9fff, Stride: 1},
+		{Lo: 0xa000, Hi: 0xa4cf, Stride: 1},
+		{Lo: 0xa960, Hi: 0xa97f, Stride: 1},
+		{Lo: 0xac00, Hi: 0xd7a3, Stride: 1},
+		{Lo: 0xf900, Hi: 0xfaff, Stride: 1},
+		{Lo: 0xfe10, Hi: 0xfe19, Stride: 1},
+		{Lo: 0xfe30, Hi: 0xfe6f, Stride: 1},
+		{Lo: 0xff00, Hi: 0xff60, Stride: 1},
+		{Lo: 0xffe0, Hi: 0xffe6, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x16fe0, Hi: 0x18cff, Stride: 1},
+		{Lo: 0x1b000, Hi: 0x1b2ff, Stride: 1},
+		{Lo: 0x1f004, Hi: 0x1f004, Stride: 1},
+		{Lo: 0x1f0cf, Hi: 0x1f0cf, Stride: 1},
+		{Lo: 0x1f18e, Hi: 0x1f18e, Stride: 1},
+		{Lo: 0x1f191, Hi: 0x1f19a, Stride: 1},
+		{Lo: 0x1f200, Hi: 0x1f64f, Stride: 1},
+		{Lo: 0x1f680, Hi: 0x1f6ff, Stride: 1},
+		{Lo: 0x1f7e0, Hi: 0x1f7eb, Stride: 1},
+		{Lo: 0x1f90c, Hi: 0x1f9ff, Stride: 1},
+		{Lo: 0x1fa70, Hi: 0x1faff, Stride: 1},
+		{Lo: 0x20000, Hi: 0x3fffd, Stride: 1},
+	},
+}
+
+// displayWidth is the number of terminal columns s takes up. Combining
+// marks and zero width characters take none, wide characters two.
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		switch {
+		case r == 0x200b || r == 0x200c || r == 0x200d || r == 0x2060 || r == 0xfeff:
+		case unicode.In(r, unicode.Mn, unicode.Me, unicode.Variation_Selector):
+		case unicode.Is(wideRanges, r):
+			width += 2
+		default:
+			width++
+		}
+	}
+
+	return width
+}
@@ -0,0 +1,210 @@
+package gen
+
+import (
+	"allium/src/lex"
+	"allium/src/parse"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func renderTerminal(source string, width int, hyperlinks bool) string {
+	parser := parse.NewParser(lex.NewLexer(source).Tokenize())
+	parser.Extensions = parse.TablesExtension | parse.StrikethroughExtension | parse.TaskListExtension
+	generator := NewTerminalGenerator(parser.Parse())
+	generator.Width = width
+	generator.Hyperlinks = hyperlinks
+
+	return generator.Terminal()
+}
+
+var ansiSequence = regexp.MustCompile("\x1b\\[[0-9;]*m|\x1b\\]8;;[^\x1b]*\x1b\\\\")
+
+// visible leaves out the styles and hyperlinks, keeping what a terminal
+// shows.
+func visible(s string) string {
+	return ansiSequence.ReplaceAllString(s, "")
+}
+
+func TestTerminalText(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		width    int
+		text     string
+	}{
+		{
+			name:     "wrapping",
+			markdown: "one two three four five six seven\n",
+			width:    14,
+			text:     "one two three\nfour five six\nseven\n",
+		},
+		{
+			name:     "unwrapped",
+			markdown: "one two three four five six seven\n",
+			width:    0,
+			text:     "one two three four five six seven\n",
+		},
+		{
+			name:     "long word",
+			markdown: "a abcdefghijkl b\n",
+			width:    6,
+			text:     "a\nabcdefghijkl\nb\n",
+		},
+		{
+			name:     "wide characters",
+			markdown: "日本語 日本語 日本語\n",
+			width:    14,
+			text:     "日本語 日本語\n日本語\n",
+		},
+		{
+			name:     "hard break",
+			markdown: "a\\\nb c\n",
+			width:    80,
+			text:     "a\nb c\n",
+		},
+		{
+			name:     "nested in a list and a quote",
+			markdown: "- one two three four\n\n> five six seven eight\n",
+			width:    12,
+			text:     "• one two\n  three four\n\n│ five six\n│ seven\n│ eight\n",
+		},
+		{
+			name:     "table",
+			markdown: "| a | b |\n| :- | -: |\n| long | 1 |\n",
+			width:    80,
+			text:     "┌──────┬───┐\n│ a    │ b │\n├──────┼───┤\n│ long │ 1 │\n└──────┴───┘\n",
+		},
+		{
+			name:     "table with wide characters",
+			markdown: "| 名前 | x |\n| :-: | - |\n| 🎉 | abcdef |\n",
+			width:    80,
+			text:     "┌──────┬────────┐\n│ 名前 │ x      │\n├──────┼────────┤\n│  🎉  │ abcdef │\n└──────┴────────┘\n",
+		},
+		{
+			name:     "code box",
+			markdown: "```go\nfmt.Println(\"日本\")\n```\n",
+			width:    80,
+			text:     "┌─ go ────────────────┐\n│ fmt.Println(\"日本\") │\n└─────────────────────┘\n",
+		},
+		{
+			name:     "links without hyperlinks",
+			markdown: "[text](https://example.com) and <https://example.com>\n",
+			width:    80,
+			text:     "text (https://example.com) and https://example.com\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if text := visible(renderTerminal(test.markdown, test.width, false)); text != test.text {
+				t.Errorf("got\n%s\nwant\n%s", text, test.text)
+			}
+		})
+	}
+}
+
+func TestTerminalCodeBoxWidth(t *testing.T) {
+	// every line of a closed box is as wide as its border
+	output := visible(renderTerminal("```\nascii\n日本語\n🎉🎉\n```\n", 80, false))
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	for _, line := range lines {
+		if width := displayWidth(line); width != displayWidth(lines[0]) {
+			t.Errorf("line %q is %d columns, want %d", line, width, displayWidth(lines[0]))
+		}
+	}
+}
+
+func TestTerminalHyperlinks(t *testing.T) {
+	output := renderTerminal("[a b](https://example.com)\n", 80, true)
+	link := "\x1b]8;;https://example.com\x1b\\"
+	end := "\x1b]8;;\x1b\\"
+	style := ansiUnderline + ansiBlue
+
+	// the space between the words stays inside the hyperlink and underline
+	want := link + style + "a" + ansiReset + end + link + style + " " + ansiReset + end + link + style + "b" + ansiReset + end + "\n"
+	if output != want {
+		t.Errorf("got %q, want %q", output, want)
+	}
+}
+
+func TestTerminalEscapesControlCharacters(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		text     string
+	}{
+		{
+			name:     "text",
+			markdown: "a \x1b[2Jb\x07c\n",
+			text:     "a ␛[2Jb␇c\n",
+		},
+		{
+			name:     "inline code",
+			markdown: "`\x1b]0;title\x07`\n",
+			text:     "␛]0;title␇\n",
+		},
+		{
+			name:     "code block",
+			markdown: "```\n\x1b[31mred\n```\n",
+			text:     "┌──────────┐\n│ ␛[31mred │\n└──────────┘\n",
+		},
+		{
+			name:     "DEL and C1 controls",
+			markdown: "a\x7fb\u009bc\n",
+			text:     "a␡b�c\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := renderTerminal(test.markdown, 80, false)
+			if text := visible(output); text != test.text {
+				t.Errorf("got %q, want %q", text, test.text)
+			}
+		})
+	}
+}
+
+func TestTerminalHyperlinkEscapesLink(t *testing.T) {
+	var link parse.LinkNode
+	link.Link = "https://example.com/\x1b\\\x07"
+	link.Nodes = []parse.Node{&parse.TextNode{Content: "a"}}
+
+	var paragraph parse.ParagraphNode
+	paragraph.Content = []parse.Node{&link}
+
+	generator := NewTerminalGenerator(&parse.DocumentNode{Nodes: []parse.Node{&paragraph}})
+	output := generator.Terminal()
+
+	// two for each end of the hyperlink, three for the style
+	if strings.Count(output, "\x1b") != 7 {
+		t.Errorf("the link added escape sequences: %q", output)
+	}
+	if !strings.Contains(output, "https://example.com/%1B\\%07") {
+		t.Errorf("the link was not percent-encoded: %q", output)
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+	}{
+		{"abc", 3},
+		{"日本語", 6},
+		{"한국어", 6},
+		{"ｆｕｌｌ", 8},
+		{"🎉", 2},
+		{"e\u0301", 1},
+		{"\u2764\ufe0f", 1},
+		{"a\u200bb", 2},
+		{"•─│", 3},
+	}
+
+	for _, test := range tests {
+		if width := displayWidth(test.text); width != test.width {
+			t.Errorf("displayWidth(%q) = %d, want %d", test.text, width, test.width)
+		}
+	}
+}
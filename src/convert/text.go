@@ -0,0 +1,259 @@
+package gen
+
+import (
+	"allium/src/parse"
+	"fmt"
+	"html"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// TextGenerator renders the text content of a document without markup,
+// keeping enough structure to read: list bullets and numbers, quoted block
+// quotes, indented code and footnotes listed at the end.
+type TextGenerator struct {
+	Document *parse.DocumentNode
+
+	// LinkUrls follows the text of links and images with their URL in
+	// parentheses, unless the text is the URL already.
+	LinkUrls bool
+}
+
+func NewTextGenerator(document *parse.DocumentNode) TextGenerator {
+	var gen = TextGenerator{}
+	gen.Document = document
+
+	return gen
+}
+
+func (g *TextGenerator) GenerateText(outputPath string) error {
+	return os.WriteFile(outputPath, []byte(g.Text()), 0644)
+}
+
+// Text renders the document and returns the resulting text.
+func (g *TextGenerator) Text() string {
+	var sections []string
+
+	if lines := g.convert_blocks(g.Document.Nodes, false); len(lines) > 0 {
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+
+	for _, footnote := range g.Document.Footnotes {
+		if footnote.Index == 0 {
+			continue
+		}
+		label := fmt.Sprintf("[%d] ", footnote.Index)
+		lines := g.convert_blocks(footnote.Nodes, false)
+		sections = append(sections, strings.Join(prefixLines(lines, label, strings.Repeat(" ", len(label))), "\n"))
+	}
+
+	if len(sections) == 0 {
+		return ""
+	}
+
+	return strings.Join(sections, "\n\n") + "\n"
+}
+
+func (g *TextGenerator) convert_blocks(nodes []parse.Node, tight bool) []string {
+	var lines []string
+
+	for _, node := range nodes {
+		block := g.convert_block(node)
+		if len(block) == 0 {
+			continue
+		}
+
+		if len(lines) > 0 && !tight {
+			lines = append(lines, "")
+		}
+		lines = append(lines, block...)
+	}
+
+	return lines
+}
+
+func (g *TextGenerator) convert_block(node parse.Node) []string {
+	switch node := node.(type) {
+	case *parse.ParagraphNode:
+		text := g.convert_inlines(node.Content)
+		if strings.TrimSpace(text) == "" {
+			return nil
+		}
+		return strings.Split(text, "\n")
+	case *parse.HeaderNode:
+		return []string{strings.ReplaceAll(g.convert_inlines(node.Content), "\n", " ")}
+	case *parse.HorizontalRuleNode:
+		return []string{"---"}
+	case *parse.InlineCodeBlockNode:
+		code := strings.Split(strings.TrimSuffix(node.Content, "\n"), "\n")
+		return prefixLines(code, "    ", "    ")
+	case *parse.HtmlBlockNode:
+		text := strings.TrimSpace(stripTags(node.Content))
+		if text == "" {
+			return nil
+		}
+		return strings.Split(text, "\n")
+	case *parse.BlockQuoteNode:
+		return prefixLines(g.convert_blocks(node.Nodes, false), "> ", "> ")
+	case *parse.ListNode:
+		return g.convert_list(node)
+	case *parse.TableNode:
+		return g.convert_table(node)
+	case *parse.DefinitionListNode:
+		return g.convert_definition_list(node)
+	}
+
+	return nil
+}
+
+func (g *TextGenerator) convert_list(list *parse.ListNode) []string {
+	var lines []string
+
+	for i, node := range list.Nodes {
+		item, ok := node.(*parse.ListItemNode)
+		if !ok {
+			continue
+		}
+
+		marker := "-"
+		if list.IsOrdered {
+			marker = fmt.Sprintf("%d.", list.Start+i)
+		}
+		if item.Checked != nil {
+			if *item.Checked {
+				marker += " [x]"
+			} else {
+				marker += " [ ]"
+			}
+		}
+
+		if i > 0 && !list.IsTight {
+			lines = append(lines, "")
+		}
+
+		content := g.convert_blocks(item.Nodes, list.IsTight)
+		if len(content) == 0 {
+			lines = append(lines, marker)
+			continue
+		}
+		lines = append(lines, prefixLines(content, marker+" ", strings.Repeat(" ", len(marker)+1))...)
+	}
+
+	return lines
+}
+
+func (g *TextGenerator) convert_definition_list(list *parse.DefinitionListNode) []string {
+	var lines []string
+	var previous parse.Node
+
+	for _, node := range list.Nodes {
+		switch node := node.(type) {
+		case *parse.TermNode:
+			if _, ok := previous.(*parse.DefinitionNode); ok {
+				lines = append(lines, "")
+			}
+			lines = append(lines, strings.ReplaceAll(g.convert_inlines(node.Content), "\n", " "))
+		case *parse.DefinitionNode:
+			if !node.IsTight {
+				lines = append(lines, "")
+			}
+			lines = append(lines, prefixLines(g.convert_blocks(node.Nodes, node.IsTight), "    ", "    ")...)
+		}
+		previous = node
+	}
+
+	return lines
+}
+
+// convert_table lines the cells up in columns, two spaces apart.
+func (g *TextGenerator) convert_table(table *parse.TableNode) []string {
+	var rows [][]string
+	var widths []int
+
+	for _, node := range table.Nodes {
+		row, ok := node.(*parse.TableRowNode)
+		if !ok {
+			continue
+		}
+
+		var cells []string
+		for i, node := range row.Nodes {
+			cell, ok := node.(*parse.TableCellNode)
+			if !ok {
+				continue
+			}
+			text := strings.ReplaceAll(g.convert_inlines(cell.Content), "\n", " ")
+			cells = append(cells, text)
+
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], utf8.RuneCountInString(text))
+		}
+		rows = append(rows, cells)
+	}
+
+	var lines []string
+	for _, row := range rows {
+		var line strings.Builder
+		for i, cell := range row {
+			if i > 0 {
+				line.WriteString("  ")
+			}
+			line.WriteString(cell)
+			if i < len(row)-1 {
+				line.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)))
+			}
+		}
+		lines = append(lines, strings.TrimRight(line.String(), " "))
+	}
+
+	return lines
+}
+
+func (g *TextGenerator) convert_inlines(nodes []parse.Node) string {
+	var out strings.Builder
+
+	for _, node := range nodes {
+		switch node := node.(type) {
+		case *parse.TextNode:
+			out.WriteString(node.Content)
+		case *parse.InlineCodeNode:
+			out.WriteString(node.Content)
+		case *parse.ItalicNode:
+			out.WriteString(g.convert_inlines(node.Nodes))
+		case *parse.BoldNode:
+			out.WriteString(g.convert_inlines(node.Nodes))
+		case *parse.StrikethroughNode:
+			out.WriteString(g.convert_inlines(node.Nodes))
+		case *parse.LinkNode:
+			text := g.convert_inlines(node.Nodes)
+			out.WriteString(text)
+			if g.LinkUrls && node.Link != "" && text != node.Link && "mailto:"+text != node.Link {
+				out.WriteString(" (" + node.Link + ")")
+			}
+		case *parse.ImageNode:
+			out.WriteString(node.LinkText)
+			if g.LinkUrls && node.Link != "" {
+				out.WriteString(" (" + node.Link + ")")
+			}
+		case *parse.HtmlInlineNode:
+			out.WriteString(stripTags(node.Content))
+		case *parse.FootnoteReferenceNode:
+			out.WriteString(fmt.Sprintf("[%d]", node.Index))
+		case *parse.NewLineNode, *parse.LineBreakNode:
+			out.WriteString("\n")
+		}
+	}
+
+	return out.String()
+}
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// stripTags drops the tags of raw HTML, keeping the text between them.
+func stripTags(markup string) string {
+	return html.UnescapeString(htmlTag.ReplaceAllString(markup, ""))
+}
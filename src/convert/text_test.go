@@ -0,0 +1,165 @@
+package gen
+
+import (
+	"allium/src/lex"
+	"allium/src/parse"
+	"testing"
+)
+
+func renderPlainText(source string, linkUrls bool) string {
+	parser := parse.NewParser(lex.NewLexer(source).Tokenize())
+	parser.Extensions = parse.TablesExtension | parse.StrikethroughExtension | parse.TaskListExtension | parse.FootnotesExtension | parse.DefinitionListExtension
+	generator := NewTextGenerator(parser.Parse())
+	generator.LinkUrls = linkUrls
+
+	return generator.Text()
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		text     string
+	}{
+		{
+			name:     "inline markup",
+			markdown: "# A *b*\n\nsome **bold**, ~~struck~~ and `code`\n",
+			text:     "A b\n\nsome bold, struck and code\n",
+		},
+		{
+			name:     "tight list",
+			markdown: "- a\n- b\n  - c\n",
+			text:     "- a\n- b\n  - c\n",
+		},
+		{
+			name:     "loose list",
+			markdown: "- a\n\n- b\n",
+			text:     "- a\n\n- b\n",
+		},
+		{
+			name:     "ordered list",
+			markdown: "9. a\n10. b\n",
+			text:     "9. a\n10. b\n",
+		},
+		{
+			name:     "list item with several paragraphs",
+			markdown: "1. a\n\n   b\n",
+			text:     "1. a\n\n   b\n",
+		},
+		{
+			name:     "task list",
+			markdown: "- [x] done\n- [ ] to do\n",
+			text:     "- [x] done\n- [ ] to do\n",
+		},
+		{
+			name:     "table",
+			markdown: "| a | bb |\n| - | -: |\n| ccc | d |\n",
+			text:     "a    bb\nccc  d\n",
+		},
+		{
+			name:     "table with wide characters and empty cells",
+			markdown: "| é | x |\n| - | - |\n| | y |\n",
+			text:     "é  x\n   y\n",
+		},
+		{
+			name:     "fenced code",
+			markdown: "a\n\n```go\nfunc main() {\n\tx  *y*\n}\n```\n",
+			text:     "a\n\n    func main() {\n    \tx  *y*\n    }\n",
+		},
+		{
+			name:     "indented code",
+			markdown: "    <b>\n\n    c\n",
+			text:     "    <b>\n\n    c\n",
+		},
+		{
+			name:     "hard breaks",
+			markdown: "a  \nb\\\nc\nd\n",
+			text:     "a\nb\nc\nd\n",
+		},
+		{
+			name:     "block quote",
+			markdown: "> a\n>\n> b\n",
+			text:     "> a\n>\n> b\n",
+		},
+		{
+			name:     "HTML",
+			markdown: "<div>\n<p>a &amp; b</p>\n</div>\n\nc <em>d</em>\n",
+			text:     "a & b\n\nc d\n",
+		},
+		{
+			name:     "footnotes",
+			markdown: "a[^1]\n\n[^1]: b\n",
+			text:     "a[1]\n\n[1] b\n",
+		},
+		{
+			name:     "definition list",
+			markdown: "a\n: b\n",
+			text:     "a\n    b\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if text := renderPlainText(test.markdown, false); text != test.text {
+				t.Errorf("got %q, want %q", text, test.text)
+			}
+		})
+	}
+}
+
+func TestTextLinks(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		text     string
+		withUrls string
+	}{
+		{
+			name:     "link",
+			markdown: "[a *b*](https://example.com)\n",
+			text:     "a b\n",
+			withUrls: "a b (https://example.com)\n",
+		},
+		{
+			name:     "autolink",
+			markdown: "<https://example.com>\n",
+			text:     "https://example.com\n",
+			withUrls: "https://example.com\n",
+		},
+		{
+			name:     "email autolink",
+			markdown: "<a@example.com>\n",
+			text:     "a@example.com\n",
+			withUrls: "a@example.com\n",
+		},
+		{
+			name:     "reference link",
+			markdown: "[a][x]\n\n[x]: /b\n",
+			text:     "a\n",
+			withUrls: "a (/b)\n",
+		},
+		{
+			name:     "image",
+			markdown: "![a cat](cat.png)\n",
+			text:     "a cat\n",
+			withUrls: "a cat (cat.png)\n",
+		},
+		{
+			name:     "image without alt text",
+			markdown: "x ![](cat.png)\n",
+			text:     "x \n",
+			withUrls: "x  (cat.png)\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if text := renderPlainText(test.markdown, false); text != test.text {
+				t.Errorf("got %q, want %q", text, test.text)
+			}
+			if text := renderPlainText(test.markdown, true); text != test.withUrls {
+				t.Errorf("with URLs got %q, want %q", text, test.withUrls)
+			}
+		})
+	}
+}
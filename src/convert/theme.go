@@ -0,0 +1,39 @@
+package gen
+
+import (
+	"embed"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//go:embed themes/*.css
+var themeFiles embed.FS
+
+// Themes lists the names of the built-in stylesheets.
+func Themes() []string {
+	entries, _ := themeFiles.ReadDir("themes")
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".css"))
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ThemeCSS returns the stylesheet of a built-in theme.
+func ThemeCSS(name string) (string, error) {
+	css, err := themeFiles.ReadFile("themes/" + name + ".css")
+	if err != nil {
+		return "", fmt.Errorf("unknown theme %q, expected one of: %s", name, strings.Join(Themes(), ", "))
+	}
+
+	return string(css), nil
+}
+
+// themeFileName is what a linked theme is written out and referred to as.
+func themeFileName(name string) string {
+	return name + ".css"
+}
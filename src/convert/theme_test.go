@@ -0,0 +1,124 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestThemes(t *testing.T) {
+	if themes := strings.Join(Themes(), " "); themes != "dark github print" {
+		t.Errorf("got themes %s, want dark github print", themes)
+	}
+}
+
+func TestThemeSelection(t *testing.T) {
+	stylesheets := make(map[string]string)
+
+	for _, theme := range Themes() {
+		t.Run(theme, func(t *testing.T) {
+			css, err := ThemeCSS(theme)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, other := range stylesheets {
+				if css == other {
+					t.Errorf("%s has the same stylesheet as %s", theme, name)
+				}
+			}
+			stylesheets[theme] = css
+
+			generator := standaloneGenerator("# a\n")
+			generator.Theme = theme
+			html, err := generator.HtmlDocument()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := "<!DOCTYPE html>\n" +
+				"<html>\n" +
+				"<head>\n" +
+				"<meta charset=\"utf-8\">\n" +
+				"<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n" +
+				"<title>a</title>\n" +
+				"<style>\n" + css + "</style>\n" +
+				"</head>\n" +
+				"<body>\n" +
+				"<h1 id=\"a\">a</h1>\n" +
+				"</body>\n" +
+				"</html>\n"
+			if html != want {
+				t.Errorf("got\n%s\nwant\n%s", html, want)
+			}
+		})
+	}
+}
+
+func TestThemeWithHighlighting(t *testing.T) {
+	generator := standaloneGenerator("# a\n")
+	generator.Theme = "dark"
+	generator.Highlight = HighlightCode
+	html, err := generator.HtmlDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	css, _ := ThemeCSS("dark")
+	if !strings.Contains(html, "<style>\n"+css+HighlightCSS+"</style>\n") {
+		t.Errorf("the theme and highlight stylesheets were not inlined in order:\n%s", html)
+	}
+}
+
+func TestLinkTheme(t *testing.T) {
+	dir := t.TempDir()
+	generator := standaloneGenerator("# a\n")
+	generator.Theme = "print"
+	generator.LinkTheme = true
+	generator.Stylesheets = []string{"site.css"}
+	if err := generator.GenerateHtml(filepath.Join(dir, "out.html")); err != nil {
+		t.Fatal(err)
+	}
+
+	html, _ := os.ReadFile(filepath.Join(dir, "out.html"))
+	links := "<link rel=\"stylesheet\" href=\"print.css\">\n<link rel=\"stylesheet\" href=\"site.css\">\n</head>\n"
+	if !strings.Contains(string(html), links) || strings.Contains(string(html), "<style>") {
+		t.Errorf("the theme was not linked before the other stylesheets:\n%s", html)
+	}
+
+	css, _ := ThemeCSS("print")
+	if data, err := os.ReadFile(filepath.Join(dir, "print.css")); err != nil || string(data) != css {
+		t.Errorf("the theme was not written next to the output: %v", err)
+	}
+}
+
+func TestUnknownTheme(t *testing.T) {
+	want := `unknown theme "solarized", expected one of: dark, github, print`
+
+	for _, name := range []string{"solarized", "../templates/standalone"} {
+		if _, err := ThemeCSS(name); err == nil {
+			t.Errorf("ThemeCSS(%q) gave no error", name)
+		}
+	}
+	if _, err := ThemeCSS("solarized"); err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+
+	generator := standaloneGenerator("# a\n")
+	generator.Theme = "solarized"
+	if _, err := generator.HtmlDocument(); err == nil || err.Error() != want {
+		t.Errorf("HtmlDocument gave error %v, want %s", err, want)
+	}
+
+	// nothing is written, linked or not
+	dir := t.TempDir()
+	for _, link := range []bool{false, true} {
+		generator.LinkTheme = link
+		if err := generator.GenerateHtml(filepath.Join(dir, "out.html")); err == nil || err.Error() != want {
+			t.Errorf("GenerateHtml with LinkTheme %v gave error %v, want %s", link, err, want)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) > 0 {
+		t.Errorf("wrote %s for an unknown theme", entries[0].Name())
+	}
+}
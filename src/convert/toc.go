@@ -0,0 +1,92 @@
+package gen
+
+import (
+	"allium/src/parse"
+)
+
+// tocPlaceholder is the paragraph text that marks where the table of
+// contents goes, at any depth. Without one the table of contents starts
+// the document.
+const tocPlaceholder = "[TOC]"
+
+// convert_toc writes the top level headings within the configured depth as
+// nested lists, each entry linking to its heading.
+func (g *Generator) convert_toc() {
+	var headers []*parse.HeaderNode
+	for _, node := range g.Document.Nodes {
+		if header, ok := node.(*parse.HeaderNode); ok && header.Level >= g.TocMinLevel && header.Level <= g.TocMaxLevel {
+			headers = append(headers, header)
+		}
+	}
+
+	if len(headers) == 0 {
+		return
+	}
+
+	g.CR()
+	g.Write("<nav class=\"toc\">\n")
+
+	// levels holds the heading level of each open list
+	var levels []int
+	for _, header := range headers {
+		for len(levels) > 1 && levels[len(levels)-2] >= header.Level {
+			levels = levels[:len(levels)-1]
+			g.Write("</li>\n</ul>\n")
+		}
+
+		switch {
+		case len(levels) == 0:
+			g.Write("<ul>\n<li>")
+			levels = append(levels, header.Level)
+		case header.Level > levels[len(levels)-1]:
+			g.Write("\n<ul>\n<li>")
+			levels = append(levels, header.Level)
+		default:
+			g.Write("</li>\n<li>")
+			levels[len(levels)-1] = header.Level
+		}
+
+		g.Write("<a href=\"#%s\">%s</a>", EscapeHtml(g.headerIds[header]), EscapeHtml(parse.PlainText(header.Content)))
+	}
+
+	for range levels {
+		g.Write("</li>\n</ul>\n")
+	}
+
+	g.Write("</nav>\n")
+}
+
+func isTocPlaceholder(node parse.Node) bool {
+	paragraph, ok := node.(*parse.ParagraphNode)
+	if !ok || len(paragraph.Content) != 1 {
+		return false
+	}
+
+	text, ok := paragraph.Content[0].(*parse.TextNode)
+	return ok && text.Content == tocPlaceholder
+}
+
+// hasTocPlaceholder reports whether a placeholder is rendered anywhere, in
+// the body or in a referenced footnote, since renderParagraph replaces one
+// however deeply it is nested.
+func (g *Generator) hasTocPlaceholder() bool {
+	find := func(node parse.Node, entering bool) parse.WalkStatus {
+		if entering && isTocPlaceholder(node) {
+			return parse.WalkStop
+		}
+		return parse.WalkContinue
+	}
+
+	for _, node := range g.Document.Nodes {
+		if parse.Walk(node, find) == parse.WalkStop {
+			return true
+		}
+	}
+	for _, footnote := range g.Document.Footnotes {
+		if footnote.Index > 0 && parse.Walk(footnote, find) == parse.WalkStop {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,81 @@
+package gen
+
+import (
+	"allium/src/lex"
+	"allium/src/parse"
+	"testing"
+)
+
+func TestTableOfContents(t *testing.T) {
+	const toc = "<nav class=\"toc\">\n<ul>\n<li><a href=\"#a\">A</a>\n<ul>\n<li><a href=\"#b\">B</a></li>\n</ul>\n</li>\n</ul>\n</nav>\n"
+	const headings = "<h1 id=\"a\">A</h1>\n<h2 id=\"b\">B</h2>\n"
+
+	tests := []struct {
+		name     string
+		markdown string
+		html     string
+	}{
+		{
+			name:     "no placeholder",
+			markdown: "# A\n\n## B\n",
+			html:     toc + headings,
+		},
+		{
+			name:     "top level placeholder",
+			markdown: "Intro\n\n[TOC]\n\n# A\n\n## B\n",
+			html:     "<p>Intro</p>\n" + toc + headings,
+		},
+		{
+			name:     "placeholder in a block quote",
+			markdown: "> [TOC]\n\n# A\n\n## B\n",
+			html:     "<blockquote>\n" + toc + "</blockquote>\n" + headings,
+		},
+		{
+			name:     "placeholder in a list",
+			markdown: "- item\n\n  [TOC]\n\n# A\n\n## B\n",
+			html:     "<ul>\n<li>\n<p>item</p>\n" + toc + "</li>\n</ul>\n" + headings,
+		},
+		{
+			name:     "placeholder with other text",
+			markdown: "[TOC] here\n\n# A\n\n## B\n",
+			html:     toc + "<p>[TOC] here</p>\n" + headings,
+		},
+		{
+			name:     "placeholder in an unreferenced footnote",
+			markdown: "# A\n\n## B\n\n[^1]: [TOC]\n",
+			html:     toc + headings,
+		},
+		{
+			name:     "no headings",
+			markdown: "[TOC]\n\ntext\n",
+			html:     "<p>text</p>\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parser := parse.NewParser(lex.NewLexer(test.markdown).Tokenize())
+			parser.Extensions = parse.FootnotesExtension
+			generator := NewGenerator(parser.Parse())
+			generator.TableOfContents = true
+
+			if html := generator.Html(); html != test.html {
+				t.Errorf("got\n%s\nwant\n%s", html, test.html)
+			}
+		})
+	}
+}
+
+func TestTableOfContentsLevels(t *testing.T) {
+	parser := parse.NewParser(lex.NewLexer("# A\n\n## B\n\n### C\n").Tokenize())
+	generator := NewGenerator(parser.Parse())
+	generator.TableOfContents = true
+	generator.TocMinLevel = 2
+	generator.TocMaxLevel = 2
+
+	want := "<nav class=\"toc\">\n<ul>\n<li><a href=\"#b\">B</a></li>\n</ul>\n</nav>\n" +
+		"<h1 id=\"a\">A</h1>\n<h2 id=\"b\">B</h2>\n<h3 id=\"c\">C</h3>\n"
+	if html := generator.Html(); html != want {
+		t.Errorf("got\n%s\nwant\n%s", html, want)
+	}
+}
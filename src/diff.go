@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const diffContext = 3
+
+type diffLine struct {
+	kind byte // ' ', '-' or '+'
+	text string
+
+	// oldLine and newLine are the 0-based line numbers the line has, or
+	// would have, on either side
+	oldLine int
+	newLine int
+}
+
+// unifiedDiff returns the changes from before to after as a unified diff
+// labelled with path, or an empty string when they are the same.
+func unifiedDiff(path string, before string, after string) string {
+	lines := diffLines(splitLines(before), splitLines(after))
+
+	var out strings.Builder
+	for start := 0; start < len(lines); start++ {
+		if lines[start].kind == ' ' {
+			continue
+		}
+
+		// extend the hunk over changes separated by little enough context
+		end := start
+		for next := start; next < len(lines) && next-end-1 <= 2*diffContext; next++ {
+			if lines[next].kind != ' ' {
+				end = next
+			}
+		}
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", path, path)
+		}
+		writeHunk(&out, lines[max(0, start-diffContext):min(len(lines), end+diffContext+1)])
+		start = end + diffContext
+	}
+
+	return out.String()
+}
+
+func writeHunk(out *strings.Builder, lines []diffLine) {
+	var oldCount, newCount int
+	for _, line := range lines {
+		if line.kind != '+' {
+			oldCount++
+		}
+		if line.kind != '-' {
+			newCount++
+		}
+	}
+
+	// an empty side is numbered by the line before it
+	oldStart, newStart := lines[0].oldLine+1, lines[0].newLine+1
+	if oldCount == 0 {
+		oldStart--
+	}
+	if newCount == 0 {
+		newStart--
+	}
+
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+	for _, line := range lines {
+		out.WriteByte(line.kind)
+		out.WriteString(line.text)
+		if !strings.HasSuffix(line.text, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// diffLines lines up the longest common subsequence of two sets of lines,
+// marking the rest as removed or added.
+func diffLines(a []string, b []string) []diffLine {
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{kind: ' ', text: a[i], oldLine: i, newLine: j})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && common[i+1][j] >= common[i][j+1]):
+			lines = append(lines, diffLine{kind: '-', text: a[i], oldLine: i, newLine: j})
+			i++
+		default:
+			lines = append(lines, diffLine{kind: '+', text: b[j], oldLine: i, newLine: j})
+			j++
+		}
+	}
+
+	return lines
+}
+
+// splitLines splits text after each newline, keeping the newlines.
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		diff   string
+	}{
+		{
+			name:   "same",
+			before: "a\nb\n",
+			after:  "a\nb\n",
+			diff:   "",
+		},
+		{
+			name:   "changed line with context",
+			before: "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			after:  "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			diff:   "--- f.md\n+++ f.md\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name:   "separate hunks",
+			before: "a\n1\n2\n3\n4\n5\n6\n7\nb\n",
+			after:  "A\n1\n2\n3\n4\n5\n6\n7\nB\n",
+			diff:   "--- f.md\n+++ f.md\n@@ -1,4 +1,4 @@\n-a\n+A\n 1\n 2\n 3\n@@ -6,4 +6,4 @@\n 5\n 6\n 7\n-b\n+B\n",
+		},
+		{
+			name:   "hunks joined by little context",
+			before: "a\n1\n2\n3\n4\n5\n6\nb\n",
+			after:  "A\n1\n2\n3\n4\n5\n6\nB\n",
+			diff:   "--- f.md\n+++ f.md\n@@ -1,8 +1,8 @@\n-a\n+A\n 1\n 2\n 3\n 4\n 5\n 6\n-b\n+B\n",
+		},
+		{
+			name:   "added to an empty file",
+			before: "",
+			after:  "a\n",
+			diff:   "--- f.md\n+++ f.md\n@@ -0,0 +1,1 @@\n+a\n",
+		},
+		{
+			name:   "everything removed",
+			before: "a\nb\n",
+			after:  "",
+			diff:   "--- f.md\n+++ f.md\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			name:   "no newline at end of file",
+			before: "a\nb",
+			after:  "a\nb\n",
+			diff:   "--- f.md\n+++ f.md\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if diff := unifiedDiff("f.md", test.before, test.after); diff != test.diff {
+				t.Errorf("got\n%s\nwant\n%s", diff, test.diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	gen "allium/src/convert"
+	"allium/src/parse"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// formatCommand rewrites Markdown files in place in a consistent style, or
+// formats standard input to standard output when no files are given. With
+// --diff or --check the files are left alone and the changes, or the names
+// of the files that would change, are printed instead.
+func formatCommand(args []string) error {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	extensionsFlag := flags.String("extensions", "", "Comma separated Markdown extensions")
+	checkFlag := flags.Bool("check", false, "List the files that are not formatted and fail if there are any")
+	diffFlag := flags.Bool("diff", false, "Print the changes formatting would make rather than making them")
+	styleFlags := addMarkdownFlags(flags)
+	flags.Parse(args)
+
+	extensions, err := parse.ParseExtensions(*extensionsFlag)
+	if err != nil {
+		return err
+	}
+
+	options, err := styleFlags.options()
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		formatted, err := formatMarkdown(string(data), extensions, options)
+		if err != nil {
+			return err
+		}
+
+		_, err = os.Stdout.WriteString(formatted)
+		return err
+	}
+
+	paths, err := markdownFiles(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	var unformatted, failed int
+	for _, path := range paths {
+		changed, err := formatFile(path, extensions, options, *checkFlag || *diffFlag, *diffFlag)
+		if err != nil {
+			fmt.Printf("%s: %v\n", path, err)
+			failed++
+			continue
+		}
+		if !changed {
+			continue
+		}
+
+		unformatted++
+		if *checkFlag && !*diffFlag {
+			fmt.Println(path)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d file(s) could not be formatted", failed)
+	}
+	if *checkFlag && unformatted > 0 {
+		return fmt.Errorf("%d file(s) need formatting", unformatted)
+	}
+
+	return nil
+}
+
+// formatFile formats a single file, reporting whether it changed. The file
+// is only rewritten when dryRun is unset, and the changes are printed when
+// showDiff is set.
+func formatFile(path string, extensions parse.Extension, options markdownOptions, dryRun bool, showDiff bool) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	formatted, err := formatMarkdown(string(data), extensions, options)
+	if err != nil {
+		return false, err
+	}
+	if formatted == string(data) {
+		return false, nil
+	}
+
+	if showDiff {
+		fmt.Print(unifiedDiff(path, string(data), formatted))
+	}
+	if dryRun {
+		return true, nil
+	}
+
+	return true, os.WriteFile(path, []byte(formatted), info.Mode().Perm())
+}
+
+// formatMarkdown rewrites a Markdown source in the style of the options,
+// failing rather than returning a result that renders differently. Wrapped
+// paragraphs only move soft line breaks, so they are compared with runs of
+// whitespace collapsed outside code, where whitespace shows.
+func formatMarkdown(source string, extensions parse.Extension, options markdownOptions) (string, error) {
+	document := parseMarkdown(source, extensions)
+
+	generator := options.markdownGenerator(document)
+	formatted := generator.Markdown()
+
+	before := renderHtml(document)
+	after := renderHtml(parseMarkdown(formatted, extensions))
+	if options.lineWidth > 0 {
+		before = collapseWhitespace(before)
+		after = collapseWhitespace(after)
+	}
+	if before != after {
+		return "", fmt.Errorf("formatting would change the rendered HTML")
+	}
+
+	return formatted, nil
+}
+
+// collapseWhitespace turns each run of whitespace in rendered HTML into a
+// single space, except inside pre and code elements.
+func collapseWhitespace(html string) string {
+	var out strings.Builder
+	code := 0
+	space := false
+
+	for i := 0; i < len(html); i++ {
+		c := html[i]
+		if c == '<' {
+			switch {
+			case isTag(html[i:], "<pre"), isTag(html[i:], "<code"):
+				code++
+			case isTag(html[i:], "</pre"), isTag(html[i:], "</code"):
+				code = max(code-1, 0)
+			}
+		}
+
+		if code == 0 && strings.IndexByte(" \t\n\r", c) >= 0 {
+			space = true
+			continue
+		}
+		if space && out.Len() > 0 {
+			out.WriteByte(' ')
+		}
+		space = false
+		out.WriteByte(c)
+	}
+
+	return out.String()
+}
+
+// isTag reports whether html starts with the tag opened by prefix, such as
+// "<pre", and not a longer tag name.
+func isTag(html string, prefix string) bool {
+	if len(html) <= len(prefix) || !strings.EqualFold(html[:len(prefix)], prefix) {
+		return false
+	}
+
+	return strings.IndexByte("> \t\n/", html[len(prefix)]) >= 0
+}
+
+func renderHtml(document *parse.DocumentNode) string {
+	generator := gen.NewGenerator(document)
+	return generator.Html()
+}
+
+// markdownFiles expands the directories among paths into the Markdown
+// files below them, skipping hidden directories.
+func markdownFiles(paths []string) ([]string, error) {
+	var files []string
+
+	for _, root := range paths {
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, root)
+			continue
+		}
+
+		err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				if path != root && strings.HasPrefix(entry.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if ext := filepath.Ext(path); ext == ".md" || ext == ".markdown" {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
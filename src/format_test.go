@@ -0,0 +1,128 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormatMarkdown(t *testing.T) {
+	tests := []struct {
+		name      string
+		source    string
+		options   markdownOptions
+		formatted string
+	}{
+		{
+			name:      "default style",
+			source:    "Title\n=====\n\n+ a\n+ b\n\n_em_ and __strong__\n",
+			formatted: "# Title\n\n- a\n- b\n\n*em* and **strong**\n",
+		},
+		{
+			name:      "already formatted",
+			source:    "# Title\n\n- a\n",
+			formatted: "# Title\n\n- a\n",
+		},
+		{
+			name:      "wrapped",
+			source:    "one two three four\n",
+			options:   markdownOptions{lineWidth: 9},
+			formatted: "one two\nthree\nfour\n",
+		},
+		{
+			name:      "wrapped around code",
+			source:    "a `x  y` b\n\n```\nkeep   this\n```\n",
+			options:   markdownOptions{lineWidth: 5},
+			formatted: "a\n`x  y`\nb\n\n```\nkeep   this\n```\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := test.options
+			options.bullet, options.emphasis = '-', '*'
+
+			formatted, err := formatMarkdown(test.source, 0, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if formatted != test.formatted {
+				t.Errorf("got %q, want %q", formatted, test.formatted)
+			}
+		})
+	}
+}
+
+func TestCollapseWhitespace(t *testing.T) {
+	tests := []struct {
+		html      string
+		collapsed string
+	}{
+		{"<p>a\nb  c</p>\n", "<p>a b c</p>"},
+		{"<p>a <code>x  y</code>\nb</p>", "<p>a <code>x  y</code> b</p>"},
+		{"<pre><code>a\n  b\n</code></pre>\n<p>c\nd</p>", "<pre><code>a\n  b\n</code></pre> <p>c d</p>"},
+		{"<pre class=\"x\">a  b</pre>", "<pre class=\"x\">a  b</pre>"},
+		{"<p><codex>a  b</codex></p>", "<p><codex>a b</codex></p>"},
+	}
+
+	for _, test := range tests {
+		if collapsed := collapseWhitespace(test.html); collapsed != test.collapsed {
+			t.Errorf("collapseWhitespace(%q) = %q, want %q", test.html, collapsed, test.collapsed)
+		}
+	}
+
+	// code that only differs in whitespace is not taken to be the same
+	if collapseWhitespace("<code>a  b</code>") == collapseWhitespace("<code>a b</code>") {
+		t.Error("whitespace inside code was collapsed")
+	}
+}
+
+func TestFormatCommand(t *testing.T) {
+	dir := t.TempDir()
+	formatted := filepath.Join(dir, "formatted.md")
+	unformatted := filepath.Join(dir, "unformatted.md")
+	os.WriteFile(formatted, []byte("# Title\n\n- a\n"), 0644)
+	os.WriteFile(unformatted, []byte("Title\n=====\n\n* a\n"), 0644)
+
+	if output, code := runAllium(t, "", "fmt", "--check", formatted); code != 0 || output != "" {
+		t.Errorf("--check of a formatted file exited %d with %q, want 0 and no output", code, output)
+	}
+
+	output, code := runAllium(t, "", "fmt", "--check", dir)
+	if code != 1 || output != unformatted+"\n" {
+		t.Errorf("--check of the directory exited %d with %q, want 1 and the unformatted file", code, output)
+	}
+
+	output, code = runAllium(t, "", "fmt", "--diff", unformatted)
+	want := "--- " + unformatted + "\n+++ " + unformatted + "\n" +
+		"@@ -1,4 +1,3 @@\n-Title\n-=====\n+# Title\n \n-* a\n+- a\n"
+	if code != 0 || output != want {
+		t.Errorf("--diff exited %d with\n%s\nwant 0 with\n%s", code, output, want)
+	}
+
+	// neither --check nor --diff touch the file
+	if data, _ := os.ReadFile(unformatted); string(data) != "Title\n=====\n\n* a\n" {
+		t.Errorf("the file was changed to %q", data)
+	}
+
+	if output, code := runAllium(t, "", "fmt", dir); code != 0 || output != "" {
+		t.Errorf("fmt exited %d with %q, want 0 and no output", code, output)
+	}
+	if data, _ := os.ReadFile(unformatted); string(data) != "# Title\n\n- a\n" {
+		t.Errorf("the file was formatted as %q", data)
+	}
+	if _, code := runAllium(t, "", "fmt", "--check", dir); code != 0 {
+		t.Errorf("--check after formatting exited %d, want 0", code)
+	}
+
+	if _, code := runAllium(t, "", "fmt", "--check", filepath.Join(dir, "missing.md")); code != 1 {
+		t.Errorf("--check of a missing file exited %d, want 1", code)
+	}
+}
+
+func TestFormatStandardInput(t *testing.T) {
+	output, code := runAllium(t, "Title\n---\n\n+ a\n", "fmt")
+	if code != 0 || output != "## Title\n\n- a\n" {
+		t.Errorf("fmt of standard input exited %d with %q", code, output)
+	}
+}
@@ -2,23 +2,39 @@ package lex
 
 import (
 	"unicode"
+	"unicode/utf8"
 )
 
 type LexState struct {
 	source  string
 	current int
 	tokens  []Token
+	line    int
+	column  int
 }
 
 func (l *LexState) Tokenize() []Token {
 	for !l.isEnd() {
+		start := l.current
 		expr := l.parseChars()
+		expr.Line = l.line
+		expr.Column = l.column
 		l.tokens = append(l.tokens, expr)
 
 		l.advance()
+
+		if expr.TokenKind == NewLine {
+			l.line++
+			l.column = 1
+		} else {
+			l.column += l.current - start
+		}
 	}
 
-	l.tokens = append(l.tokens, newToken(Eof, ""))
+	eof := newToken(Eof, "")
+	eof.Line = l.line
+	eof.Column = l.column
+	l.tokens = append(l.tokens, eof)
 	return l.tokens
 }
 
@@ -28,6 +44,8 @@ func (l *LexState) parseChars() Token {
 		return l.parseIdentifier()
 	case unicode.IsDigit(l.currentChar()):
 		return l.parseNumeric()
+	case l.currentChar() == '\r':
+		return l.parseLineEnding()
 	default:
 		return l.parseSymbol()
 	}
@@ -38,7 +56,6 @@ func (l *LexState) parseSymbol() Token {
 	symbolMap["#"] = Hashtag
 	symbolMap[","] = Comma
 	symbolMap["\n"] = NewLine
-	symbolMap["\r"] = CarriageReturn
 	symbolMap[" "] = WhiteSpace
 	symbolMap["!"] = Exclamation
 	symbolMap["*"] = Star
@@ -52,8 +69,11 @@ func (l *LexState) parseSymbol() Token {
 	symbolMap[">"] = GreaterThan
 	symbolMap["`"] = BackTick
 	symbolMap["."] = Dot
+	symbolMap["|"] = Pipe
+	symbolMap["~"] = Tilde
 
-	c := string(l.currentChar())
+	_, size := utf8.DecodeRuneInString(l.source[l.current:])
+	c := l.source[l.current : l.current+size]
 
 	value, ok := symbolMap[c]
 	if !ok {
@@ -87,6 +107,17 @@ func (l *LexState) parseNumeric() Token {
 	return newToken(Number, lexeme)
 }
 
+// parseLineEnding normalises CRLF and lone CR line endings to a single
+// NewLine token so the parser only ever has to deal with LF.
+func (l *LexState) parseLineEnding() Token {
+	l.advance()
+	if l.currentChar() != '\n' {
+		l.recede()
+	}
+
+	return newToken(NewLine, "\n")
+}
+
 func newToken(lexType TokenType, value string) Token {
 	var token Token
 	token.Value = value
@@ -99,24 +130,39 @@ func (l *LexState) isEnd() bool {
 	return l.current >= len(l.source)
 }
 
+// currentChar decodes the UTF-8 character at the current position, so
+// multibyte characters are never split across tokens.
 func (l *LexState) currentChar() rune {
 	if l.isEnd() {
 		return 0
 	}
-	return rune(l.source[l.current])
+	c, _ := utf8.DecodeRuneInString(l.source[l.current:])
+	return c
 }
 
 func (l *LexState) advance() {
-	l.current++
+	if l.isEnd() {
+		l.current++
+		return
+	}
+	_, size := utf8.DecodeRuneInString(l.source[l.current:])
+	l.current += size
 }
 
 func (l *LexState) recede() {
-	l.current--
+	if l.current > len(l.source) {
+		l.current--
+		return
+	}
+	_, size := utf8.DecodeLastRuneInString(l.source[:l.current])
+	l.current -= size
 }
 
 func NewLexer(source string) *LexState {
 	var lexer LexState
 	lexer.source = source
+	lexer.line = 1
+	lexer.column = 1
 
 	return &lexer
 }
@@ -47,6 +47,10 @@ func (t TokenType) String() string {
 		return "Dot"
 	case Number:
 		return "Number"
+	case Pipe:
+		return "Pipe"
+	case Tilde:
+		return "Tilde"
 	default:
 		return "Unknown"
 	}
@@ -55,7 +59,7 @@ func (t TokenType) String() string {
 func PrintTokens(tokens []Token) {
 	for i, token := range tokens {
 		escapedValue := escapeSpecialChars(string(token.Value))
-		fmt.Printf("%d: %s %s\n", i, escapedValue, token.TokenKind)
+		fmt.Printf("%d: %d:%d %s %s\n", i, token.Line, token.Column, escapedValue, token.TokenKind)
 	}
 }
 
@@ -22,11 +22,16 @@ const (
 	Dot
 	Tab
 	Minus
+	Pipe
+	Tilde
 	Eof
 	None
 )
 
+// Token is a lexeme with the 1-based line and byte column it starts at.
 type Token struct {
 	TokenKind TokenType
 	Value     string
+	Line      int
+	Column    int
 }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"allium/src/lint"
+	"allium/src/parse"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// defaultLintConfig is read by lint when no --config is given, if present.
+const defaultLintConfig = ".allium-lint.json"
+
+// lintCommand checks Markdown files against the lint rules, printing each
+// violation as text or JSON and failing when there are any.
+func lintCommand(args []string) error {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	extensionsFlag := flags.String("extensions", "", "Comma separated Markdown extensions")
+	configFlag := flags.String("config", "", "JSON file turning rules on or off, "+defaultLintConfig+" by default")
+	formatFlag := flags.String("format", "text", "Output format: text or json")
+	rulesFlag := flags.Bool("rules", false, "List the rules and exit")
+	flags.Parse(args)
+
+	if *rulesFlag {
+		for _, rule := range lint.Rules() {
+			fmt.Printf("%-24s %s\n", rule.Name, rule.Description)
+		}
+		return nil
+	}
+
+	if flags.NArg() == 0 {
+		return fmt.Errorf("Usage: go run ./src lint [--config=<file>] [--format=text | json] [--extensions=<names>] <file or directory> ...")
+	}
+	if *formatFlag != "text" && *formatFlag != "json" {
+		return fmt.Errorf("Invalid format: %s", *formatFlag)
+	}
+
+	extensions, err := parse.ParseExtensions(*extensionsFlag)
+	if err != nil {
+		return err
+	}
+
+	var config lint.Config
+	configPath := *configFlag
+	if configPath == "" {
+		if _, err := os.Stat(defaultLintConfig); err == nil {
+			configPath = defaultLintConfig
+		}
+	}
+	if configPath != "" {
+		if config, err = lint.LoadConfig(configPath); err != nil {
+			return err
+		}
+	}
+
+	paths, err := markdownFiles(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	violations := []lint.Violation{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		for _, violation := range lint.Lint(parseMarkdown(string(data), extensions), string(data), config) {
+			violation.File = path
+			violations = append(violations, violation)
+		}
+	}
+
+	if *formatFlag == "json" {
+		data, err := json.MarshalIndent(violations, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, violation := range violations {
+			fmt.Println(violation)
+		}
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("%d problem(s) found", len(violations))
+	}
+
+	return nil
+}
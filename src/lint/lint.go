@@ -0,0 +1,111 @@
+// Package lint checks Markdown documents against a set of style rules.
+package lint
+
+import (
+	"allium/src/parse"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Violation is a place in a document that breaks a rule. File is left for
+// the caller to fill in.
+type Violation struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s (%s)", v.File, v.Line, v.Column, v.Message, v.Rule)
+}
+
+func violation(rule string, position parse.Position, format string, args ...any) Violation {
+	var v Violation
+	v.Line = position.Line
+	v.Column = position.Column
+	v.Rule = rule
+	v.Message = fmt.Sprintf(format, args...)
+
+	return v
+}
+
+// Rule checks a document, along with the source it was parsed from, for a
+// single kind of problem.
+type Rule struct {
+	Name        string
+	Description string
+	Check       func(document *parse.DocumentNode, source string) []Violation
+}
+
+// Rules returns the built-in rules.
+func Rules() []Rule {
+	return append([]Rule(nil), rules...)
+}
+
+// Config turns rules on and off by name. Rules it does not mention are on.
+type Config struct {
+	Rules map[string]bool `json:"rules"`
+}
+
+// Enabled reports whether the rule with the given name is turned on.
+func (c Config) Enabled(name string) bool {
+	enabled, ok := c.Rules[name]
+	return !ok || enabled
+}
+
+// LoadConfig reads a JSON configuration file such as
+//
+//	{"rules": {"no-bare-urls": false}}
+func LoadConfig(path string) (Config, error) {
+	var config Config
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("%s: %v", path, err)
+	}
+
+	for name := range config.Rules {
+		if !isRule(name) {
+			return config, fmt.Errorf("%s: unknown rule: %s", path, name)
+		}
+	}
+
+	return config, nil
+}
+
+func isRule(name string) bool {
+	for _, rule := range rules {
+		if rule.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Lint checks a document against the enabled rules, returning the
+// violations in source order.
+func Lint(document *parse.DocumentNode, source string, config Config) []Violation {
+	var violations []Violation
+	for _, rule := range rules {
+		if config.Enabled(rule.Name) {
+			violations = append(violations, rule.Check(document, source)...)
+		}
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].Line != violations[j].Line {
+			return violations[i].Line < violations[j].Line
+		}
+		return violations[i].Column < violations[j].Column
+	})
+
+	return violations
+}
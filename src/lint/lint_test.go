@@ -0,0 +1,198 @@
+package lint_test
+
+import (
+	"allium/src/lex"
+	"allium/src/lint"
+	"allium/src/parse"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// only turns on a single rule.
+func only(name string) lint.Config {
+	var config lint.Config
+	config.Rules = make(map[string]bool)
+	for _, rule := range lint.Rules() {
+		config.Rules[rule.Name] = rule.Name == name
+	}
+
+	return config
+}
+
+func lintMarkdown(source string, config lint.Config) []lint.Violation {
+	parser := parse.NewParser(lex.NewLexer(source).Tokenize())
+	parser.Extensions = parse.TablesExtension
+	return lint.Lint(parser.Parse(), source, config)
+}
+
+// positions lists violations as line:column.
+func positions(violations []lint.Violation) string {
+	var s []string
+	for _, v := range violations {
+		s = append(s, fmt.Sprintf("%d:%d", v.Line, v.Column))
+	}
+
+	return strings.Join(s, " ")
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		rule      string
+		name      string
+		markdown  string
+		positions string
+	}{
+		{"heading-increment", "one level at a time", "# a\n\n## b\n\n### c\n\n# d\n", ""},
+		{"heading-increment", "skipped level", "# a\n\n### b\n", "3:1"},
+		{"heading-increment", "skipped after going back up", "# a\n\n## b\n\n# c\n\n#### d\n", "7:1"},
+		{"heading-increment", "setext", "a\n=\n\nb\n-\n", ""},
+
+		{"no-duplicate-heading", "different text", "# a\n\n## b\n", ""},
+		{"no-duplicate-heading", "same text", "# a\n\n## b\n\n## a\n", "5:1"},
+		{"no-duplicate-heading", "same text with emphasis", "# a b\n\n# a *b*\n", "3:1"},
+
+		{"no-bare-urls", "autolink", "see <https://example.com>\n", ""},
+		{"no-bare-urls", "link", "see [https://example.com](https://example.com)\n", ""},
+		{"no-bare-urls", "inline code", "see `https://example.com`\n", ""},
+		{"no-bare-urls", "bare URL", "see https://example.com.\n", "1:5"},
+		{"no-bare-urls", "bare URLs on later lines", "a\n\nb http://a.com and http://b.com\n", "3:3 3:20"},
+
+		{"image-alt-text", "alt text", "![a cat](cat.png)\n", ""},
+		{"image-alt-text", "no alt text", "a ![](cat.png)\n", "1:3"},
+		{"image-alt-text", "blank alt text", "![ ](cat.png)\n\n![](dog.png)\n", "1:1 3:1"},
+
+		{"no-trailing-whitespace", "clean", "a\nb\n", ""},
+		{"no-trailing-whitespace", "hard break", "a  \nb\n", ""},
+		{"no-trailing-whitespace", "spaces", "a \nb   \n", "1:2 2:2"},
+		{"no-trailing-whitespace", "tab", "a\t\n", "1:2"},
+		{"no-trailing-whitespace", "blank line", "a\n  \nb\n", "2:1"},
+		{"no-trailing-whitespace", "CRLF", "a\r\nb \r\nc  \r\n", "2:2"},
+		{"no-trailing-whitespace", "CR", "a\rb \rc  \r", "2:2"},
+		{"no-trailing-whitespace", "CR before the whitespace", "a \r\r\n \r", "1:2 3:1"},
+
+		{"list-marker-style", "same marker", "- a\n\n1. b\n\n- c\n", ""},
+		{"list-marker-style", "nested", "- a\n  - b\n", ""},
+		{"list-marker-style", "different markers", "- a\n\ntext\n\n* b\n\ntext\n\n+ c\n", "5:1 9:1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.rule+"/"+test.name, func(t *testing.T) {
+			violations := lintMarkdown(test.markdown, only(test.rule))
+			for _, violation := range violations {
+				if violation.Rule != test.rule {
+					t.Errorf("violation of %s with only %s turned on", violation.Rule, test.rule)
+				}
+			}
+			if got := positions(violations); got != test.positions {
+				t.Errorf("got violations at %q, want %q", got, test.positions)
+			}
+		})
+	}
+}
+
+func TestLintOrder(t *testing.T) {
+	violations := lintMarkdown("# a \n\n### a\n\n![](x.png) https://example.com\n", lint.Config{})
+
+	var rules []string
+	for _, violation := range violations {
+		rules = append(rules, violation.Rule)
+	}
+
+	want := "no-trailing-whitespace heading-increment no-duplicate-heading image-alt-text no-bare-urls"
+	if got := strings.Join(rules, " "); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		disabled []string
+		err      string
+	}{
+		{
+			name:   "empty",
+			config: `{}`,
+		},
+		{
+			name:     "rules turned off",
+			config:   `{"rules": {"no-bare-urls": false, "image-alt-text": false, "heading-increment": true}}`,
+			disabled: []string{"no-bare-urls", "image-alt-text"},
+		},
+		{
+			name:   "unknown rule",
+			config: `{"rules": {"no-such-rule": false}}`,
+			err:    "unknown rule: no-such-rule",
+		},
+		{
+			name:   "invalid JSON",
+			config: `{"rules": `,
+			err:    "unexpected end of JSON input",
+		},
+		{
+			name:   "wrong type",
+			config: `{"rules": {"no-bare-urls": "off"}}`,
+			err:    "cannot unmarshal string",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "lint.json")
+			if err := os.WriteFile(path, []byte(test.config), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			config, err := lint.LoadConfig(path)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) || !strings.HasPrefix(err.Error(), path) {
+					t.Fatalf("got error %v, want %s: ...%s...", err, path, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, rule := range lint.Rules() {
+				disabled := false
+				for _, name := range test.disabled {
+					disabled = disabled || name == rule.Name
+				}
+				if config.Enabled(rule.Name) == disabled {
+					t.Errorf("%s enabled is %v, want %v", rule.Name, config.Enabled(rule.Name), !disabled)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	if _, err := lint.LoadConfig(filepath.Join(t.TempDir(), "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("got %v, want a file not found error", err)
+	}
+}
+
+func TestViolationJSON(t *testing.T) {
+	var violation lint.Violation
+	violation.File = "a.md"
+	violation.Line = 3
+	violation.Column = 5
+	violation.Rule = "no-bare-urls"
+	violation.Message = "bare URL"
+
+	data, err := json.Marshal([]lint.Violation{violation, {Line: 1, Column: 1, Rule: "r", Message: "m"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `[{"file":"a.md","line":3,"column":5,"rule":"no-bare-urls","message":"bare URL"},{"line":1,"column":1,"rule":"r","message":"m"}]`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}
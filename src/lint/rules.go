@@ -0,0 +1,139 @@
+package lint
+
+import (
+	"allium/src/parse"
+	"regexp"
+	"strings"
+)
+
+var rules = []Rule{
+	{Name: "heading-increment", Description: "Heading levels only go up one at a time", Check: checkHeadingIncrement},
+	{Name: "no-duplicate-heading", Description: "No two headings have the same text", Check: checkDuplicateHeading},
+	{Name: "no-bare-urls", Description: "URLs are written as links or autolinks rather than plain text", Check: checkBareUrls},
+	{Name: "image-alt-text", Description: "Images have alt text", Check: checkImageAltText},
+	{Name: "no-trailing-whitespace", Description: "Lines do not end in whitespace, other than two spaces for a hard line break", Check: checkTrailingWhitespace},
+	{Name: "list-marker-style", Description: "Bullet lists all use the same marker", Check: checkListMarkerStyle},
+}
+
+func checkHeadingIncrement(document *parse.DocumentNode, source string) []Violation {
+	var violations []Violation
+
+	previous := 0
+	for _, node := range parse.FindAll(document, parse.HeaderKind) {
+		header := node.(*parse.HeaderNode)
+		if previous > 0 && header.Level > previous+1 {
+			violations = append(violations, violation("heading-increment", header.Span().Start, "heading level %d skips level %d", header.Level, previous+1))
+		}
+		previous = header.Level
+	}
+
+	return violations
+}
+
+func checkDuplicateHeading(document *parse.DocumentNode, source string) []Violation {
+	var violations []Violation
+
+	seen := make(map[string]parse.Position)
+	for _, node := range parse.FindAll(document, parse.HeaderKind) {
+		header := node.(*parse.HeaderNode)
+		text := strings.TrimSpace(parse.PlainText(header.Content))
+		if first, ok := seen[text]; ok {
+			violations = append(violations, violation("no-duplicate-heading", header.Span().Start, "duplicate heading %q, first used at line %d", text, first.Line))
+			continue
+		}
+		seen[text] = header.Span().Start
+	}
+
+	return violations
+}
+
+var bareUrl = regexp.MustCompile(`https?://[^\s<>]+`)
+
+func checkBareUrls(document *parse.DocumentNode, source string) []Violation {
+	var violations []Violation
+
+	parse.Walk(document, func(node parse.Node, entering bool) parse.WalkStatus {
+		switch node := node.(type) {
+		case *parse.LinkNode:
+			// link text may well spell out the destination
+			return parse.WalkSkipChildren
+		case *parse.TextNode:
+			if !entering {
+				break
+			}
+			for _, match := range bareUrl.FindAllStringIndex(node.Content, -1) {
+				url := strings.TrimRight(node.Content[match[0]:match[1]], ".,;:!?")
+				violations = append(violations, violation("no-bare-urls", textPosition(node, match[0]), "bare URL %s, write <%s> or a link", url, url))
+			}
+		}
+		return parse.WalkContinue
+	})
+
+	return violations
+}
+
+// textPosition finds an offset of a text node's content in the source,
+// which is only possible when the content was not unescaped on the way in.
+func textPosition(text *parse.TextNode, offset int) parse.Position {
+	span := text.Span()
+	if span.Start.Line == span.End.Line && span.End.Column-span.Start.Column+1 == len(text.Content) {
+		span.Start.Column += offset
+	}
+
+	return span.Start
+}
+
+func checkImageAltText(document *parse.DocumentNode, source string) []Violation {
+	var violations []Violation
+
+	for _, node := range parse.FindAll(document, parse.ImageKind) {
+		image := node.(*parse.ImageNode)
+		if strings.TrimSpace(image.LinkText) == "" {
+			violations = append(violations, violation("image-alt-text", image.Span().Start, "image %s has no alt text", image.Link))
+		}
+	}
+
+	return violations
+}
+
+func checkTrailingWhitespace(document *parse.DocumentNode, source string) []Violation {
+	var violations []Violation
+
+	// the lexer ends lines at CRLF and a lone CR as well as LF
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	source = strings.ReplaceAll(source, "\r", "\n")
+
+	for i, line := range strings.Split(source, "\n") {
+		content := strings.TrimRight(line, " \t")
+		trailing := line[len(content):]
+		if trailing == "" || (trailing == "  " && content != "") {
+			continue
+		}
+
+		position := parse.Position{Line: i + 1, Column: len(content) + 1}
+		violations = append(violations, violation("no-trailing-whitespace", position, "trailing whitespace"))
+	}
+
+	return violations
+}
+
+func checkListMarkerStyle(document *parse.DocumentNode, source string) []Violation {
+	var violations []Violation
+
+	var first *parse.ListNode
+	for _, node := range parse.FindAll(document, parse.ListKind) {
+		list := node.(*parse.ListNode)
+		if list.IsOrdered {
+			continue
+		}
+		if first == nil {
+			first = list
+			continue
+		}
+		if list.Marker != first.Marker {
+			violations = append(violations, violation("list-marker-style", list.Span().Start, "list marker %q differs from %q used at line %d", list.Marker, first.Marker, first.Span().Start.Line))
+		}
+	}
+
+	return violations
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLintCommand(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.md")
+	config := filepath.Join(dir, "lint.json")
+	os.WriteFile(path, []byte("# a\n\n### b \n"), 0644)
+	os.WriteFile(config, []byte(`{"rules": {"no-trailing-whitespace": false}}`), 0644)
+
+	output, code := runAllium(t, "", "lint", path)
+	want := path + ":3:1: heading level 3 skips level 2 (heading-increment)\n" +
+		path + ":3:6: trailing whitespace (no-trailing-whitespace)\n"
+	if code != 1 || output != want {
+		t.Errorf("lint exited %d with\n%s\nwant 1 with\n%s", code, output, want)
+	}
+
+	output, code = runAllium(t, "", "lint", "--format=json", "--config="+config, path)
+	want = "[\n" +
+		"  {\n" +
+		"    \"file\": \"" + path + "\",\n" +
+		"    \"line\": 3,\n" +
+		"    \"column\": 1,\n" +
+		"    \"rule\": \"heading-increment\",\n" +
+		"    \"message\": \"heading level 3 skips level 2\"\n" +
+		"  }\n" +
+		"]\n"
+	if code != 1 || output != want {
+		t.Errorf("--format=json exited %d with\n%s\nwant 1 with\n%s", code, output, want)
+	}
+
+	// no violations is still a JSON array
+	os.WriteFile(path, []byte("# a\n"), 0644)
+	if output, code := runAllium(t, "", "lint", "--format=json", path); code != 0 || output != "[]\n" {
+		t.Errorf("--format=json of a clean file exited %d with %q, want 0 and []", code, output)
+	}
+
+	if _, code := runAllium(t, "", "lint", "--config="+filepath.Join(dir, "missing.json"), path); code != 1 {
+		t.Errorf("lint with a missing config exited %d, want 1", code)
+	}
+}
@@ -6,57 +6,370 @@ import (
 	"allium/src/parse"
 	"flag"
 	"fmt"
+	"html/template"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 const (
 	toHTML = "tohtml"
 	toMD   = "tomd"
+	toText = "totext"
+	toTerm = "toterm"
+
+	formatHTML = "html"
+	formatJSON = "json"
 )
 
 func main() {
-	convertFlag := flag.String("convert", "", "Conversion type: tohtml or tomd")
+	if runCommand(os.Args[1:]) {
+		return
+	}
+
+	convertFlag := flag.String("convert", "", "Conversion type: tohtml, tomd, totext or toterm")
 	pathFlag := flag.String("path", "", "Source path")
 	outputFlag := flag.String("output", "", "output path")
+	extensionsFlag := flag.String("extensions", "", "Comma separated Markdown extensions: tables, strikethrough, tasklist, footnotes, deflist, frontmatter, headerids")
+	tocFlag := flag.Bool("toc", false, "Add a table of contents, in place of a [TOC] paragraph or at the start")
+	tocMinFlag := flag.Int("toc-min", 1, "Smallest heading level in the table of contents")
+	tocMaxFlag := flag.Int("toc-max", 6, "Largest heading level in the table of contents")
+	standaloneFlag := flag.Bool("standalone", false, "Write a complete HTML document rather than a fragment")
+	templateFlag := flag.String("template", "", "HTML template for standalone output, in place of the built-in one")
+	stylesheetFlag := flag.String("stylesheet", "", "Comma separated stylesheet URLs to link from standalone output")
+	themeFlag := flag.String("theme", "", "Built-in stylesheet for standalone output: "+strings.Join(gen.Themes(), ", "))
+	linkThemeFlag := flag.Bool("link-theme", false, "Write the theme next to the output and link it rather than inlining it")
+	highlightFlag := flag.Bool("highlight", false, "Highlight fenced code in Go, JSON, YAML, shell, Python and SQL")
+	baseUrlFlag := flag.String("base-url", "", "Resolve relative links against this URL")
+	imageCdnFlag := flag.String("image-cdn", "", "Resolve relative image sources against this CDN URL")
+	shiftHeadingsFlag := flag.Int("shift-headings", 0, "Move every heading down by this many levels, or up when negative")
+	formatFlag := flag.String("format", formatHTML, "Output format: html, or json for the parsed syntax tree")
+	styleFlags := addMarkdownFlags(flag.CommandLine)
+	linkUrlsFlag := flag.Bool("link-urls", false, "Follow link text with the URL in totext and toterm output")
+	widthFlag := flag.Int("width", 0, "Wrap toterm output at this width, the terminal width by default")
 	flag.Parse()
 
-	if *convertFlag == "" || *pathFlag == "" || *outputFlag == "" {
-		fmt.Println("Usage: go run ./src --convert=[tohtml | tomd] --path=<source> --output=*.[html | md]")
+	if (*convertFlag == "" && *formatFlag != formatJSON) || *pathFlag == "" || *outputFlag == "" {
+		fmt.Println("Usage: go run ./src --convert=[tohtml | tomd | totext | toterm] [--format=html | json] --path=<source> --output=*.[html | md | txt | ansi] [--extensions=tables,strikethrough,tasklist,footnotes,deflist,frontmatter,headerids] [--toc] [--toc-min=1] [--toc-max=6] [--standalone [--template=<file>] [--stylesheet=<url,...>] [--theme=<name> [--link-theme]]] [--highlight] [--base-url=<url>] [--image-cdn=<url>] [--shift-headings=<n>] [--bullet=- | * | +] [--emphasis=* | _] [--heading-style=atx | setext] [--line-width=<n>] [--link-urls] [--width=<n>]")
+		fmt.Println("       go run ./src tokens <source>")
+		fmt.Println("       go run ./src ast [--extensions=<names>] <source>")
+		fmt.Println("       go run ./src fmt [--check] [--diff] [--extensions=<names>] [--bullet=- | * | +] [--emphasis=* | _] [--heading-style=atx | setext] [--line-width=<n>] [<file or directory> ...]")
+		fmt.Println("       go run ./src view [--width=<n>] [--link-urls] [--extensions=<names>] <source>")
+		fmt.Println("       go run ./src lint [--config=<file>] [--format=text | json] [--extensions=<names>] <file or directory> ...")
+		return
+	}
+
+	extensions, err := parse.ParseExtensions(*extensionsFlag)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if *tocMinFlag < 1 || *tocMaxFlag > 6 || *tocMinFlag > *tocMaxFlag {
+		fmt.Println("Table of contents levels must satisfy 1 <= toc-min <= toc-max <= 6")
+		return
+	}
+
+	var options htmlOptions
+	options.toc = *tocFlag
+	options.tocMin = *tocMinFlag
+	options.tocMax = *tocMaxFlag
+	options.standalone = *standaloneFlag || *templateFlag != "" || *themeFlag != ""
+	options.template = *templateFlag
+	options.theme = *themeFlag
+	options.linkTheme = *linkThemeFlag
+	options.highlight = *highlightFlag
+	for _, stylesheet := range strings.Split(*stylesheetFlag, ",") {
+		if stylesheet = strings.TrimSpace(stylesheet); stylesheet != "" {
+			options.stylesheets = append(options.stylesheets, stylesheet)
+		}
+	}
+
+	var transformers []parse.Transformer
+	if *baseUrlFlag != "" {
+		transformers = append(transformers, parse.AbsoluteLinks(*baseUrlFlag))
+	}
+	if *imageCdnFlag != "" {
+		transformers = append(transformers, parse.ImageCDN(*imageCdnFlag))
+	}
+	if *shiftHeadingsFlag != 0 {
+		transformers = append(transformers, parse.ShiftHeadings(*shiftHeadingsFlag))
+	}
+	options.transformers = transformers
+
+	switch *formatFlag {
+	case formatHTML:
+	case formatJSON:
+		if err := exportJSON(*pathFlag, *outputFlag, extensions, transformers); err != nil {
+			fmt.Printf("Error exporting JSON: %v\n", err)
+		}
+		return
+	default:
+		fmt.Printf("Invalid format: %s\n", *formatFlag)
 		return
 	}
 
 	switch *convertFlag {
 	case toHTML:
-		err := convertToHTML(*pathFlag, *outputFlag)
+		err := convertToHTML(*pathFlag, *outputFlag, extensions, options)
 		if err != nil {
 			fmt.Printf("Error converting to HTML: %v\n", err)
 		}
 	case toMD:
-		fmt.Println("tomd not currently supported.")
+		style, err := styleFlags.options()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		style.transformers = transformers
+
+		if err := convertToMarkdown(*pathFlag, *outputFlag, extensions, style); err != nil {
+			fmt.Printf("Error converting to Markdown: %v\n", err)
+		}
+	case toText:
+		if err := convertToText(*pathFlag, *outputFlag, extensions, transformers, *linkUrlsFlag); err != nil {
+			fmt.Printf("Error converting to text: %v\n", err)
+		}
+	case toTerm:
+		if err := convertToTerminal(*pathFlag, *outputFlag, extensions, transformers, *widthFlag, *linkUrlsFlag); err != nil {
+			fmt.Printf("Error converting to terminal text: %v\n", err)
+		}
 	default:
 		fmt.Printf("Invalid convert type: %s\n", *convertFlag)
 	}
 }
 
-func convertToHTML(filepath string, outputPath string) error {
-	data, err := os.ReadFile(filepath)
+// htmlOptions carries the command line settings for the HTML generator.
+type htmlOptions struct {
+	toc         bool
+	tocMin      int
+	tocMax      int
+	standalone  bool
+	template    string
+	stylesheets []string
+	theme       string
+	linkTheme   bool
+	highlight   bool
+
+	transformers []parse.Transformer
+}
+
+// markdownOptions carries the command line settings for the Markdown
+// generator.
+type markdownOptions struct {
+	bullet       byte
+	emphasis     byte
+	headingStyle gen.HeadingStyle
+	lineWidth    int
+
+	transformers []parse.Transformer
+}
+
+// markdownFlags holds the command line flags that set the Markdown style.
+type markdownFlags struct {
+	bullet       *string
+	emphasis     *string
+	headingStyle *string
+	lineWidth    *int
+}
+
+func addMarkdownFlags(flags *flag.FlagSet) markdownFlags {
+	var f markdownFlags
+	f.bullet = flags.String("bullet", "-", "Bullet list marker for Markdown output: -, * or +")
+	f.emphasis = flags.String("emphasis", "*", "Emphasis marker for Markdown output: * or _")
+	f.headingStyle = flags.String("heading-style", "atx", "Heading style for Markdown output: atx, or setext for levels 1 and 2")
+	f.lineWidth = flags.Int("line-width", 0, "Wrap Markdown paragraphs at this width, or not at all when 0")
+
+	return f
+}
+
+func (f markdownFlags) options() (markdownOptions, error) {
+	var o markdownOptions
+	if *f.bullet != "-" && *f.bullet != "*" && *f.bullet != "+" {
+		return o, fmt.Errorf("invalid bullet: %s", *f.bullet)
+	}
+	if *f.emphasis != "*" && *f.emphasis != "_" {
+		return o, fmt.Errorf("invalid emphasis marker: %s", *f.emphasis)
+	}
+	if *f.lineWidth < 0 {
+		return o, fmt.Errorf("invalid line width: %d", *f.lineWidth)
+	}
+
+	switch *f.headingStyle {
+	case "atx":
+		o.headingStyle = gen.ATXHeadings
+	case "setext":
+		o.headingStyle = gen.SetextHeadings
+	default:
+		return o, fmt.Errorf("invalid heading style: %s", *f.headingStyle)
+	}
+
+	o.bullet = (*f.bullet)[0]
+	o.emphasis = (*f.emphasis)[0]
+	o.lineWidth = *f.lineWidth
+
+	return o, nil
+}
+
+// markdownGenerator makes a Markdown generator for a document in the style
+// of the options.
+func (o markdownOptions) markdownGenerator(document *parse.DocumentNode) gen.MarkdownGenerator {
+	generator := gen.NewMarkdownGenerator(document)
+	generator.BulletChar = o.bullet
+	generator.EmphasisChar = o.emphasis
+	generator.HeadingStyle = o.headingStyle
+	generator.LineWidth = o.lineWidth
+
+	return generator
+}
+
+// readDocument parses a Markdown source, or decodes a syntax tree written
+// with --format=json when the source has a .json extension, and then
+// applies the transformers.
+func readDocument(path string, extensions parse.Extension, transformers []parse.Transformer) (*parse.DocumentNode, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	lexer := lex.NewLexer(string(data))
+	var document *parse.DocumentNode
+	if filepath.Ext(path) == ".json" {
+		document, err = parse.DecodeJSON(data)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		document = parseMarkdown(string(data), extensions)
+	}
+
+	if err := parse.Transform(document, transformers...); err != nil {
+		return nil, err
+	}
+
+	return document, nil
+}
+
+func parseMarkdown(source string, extensions parse.Extension) *parse.DocumentNode {
+	lexer := lex.NewLexer(source)
 	tokens := lexer.Tokenize()
-	// lex.PrintTokens(tokens)
 
 	parser := parse.NewParser(tokens)
-	exprs := parser.Parse()
-	// parse.PrintNodes(exprs)
+	parser.Extensions = extensions
+	return parser.Parse()
+}
+
+func exportJSON(path string, outputPath string, extensions parse.Extension, transformers []parse.Transformer) error {
+	document, err := readDocument(path, extensions, transformers)
+	if err != nil {
+		return err
+	}
+
+	data, err := parse.EncodeJSON(document)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(outputPath, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Finished exporting Markdown to JSON\n")
+	fmt.Printf("Output at %s\n", outputPath)
+
+	return nil
+}
+
+func convertToHTML(path string, outputPath string, extensions parse.Extension, options htmlOptions) error {
+	document, err := readDocument(path, extensions, options.transformers)
+	if err != nil {
+		return err
+	}
+
+	generator := gen.NewGenerator(document)
+	generator.TableOfContents = options.toc
+	generator.TocMinLevel = options.tocMin
+	generator.TocMaxLevel = options.tocMax
+	generator.Standalone = options.standalone
+	generator.Stylesheets = options.stylesheets
+	generator.Theme = options.theme
+	generator.LinkTheme = options.linkTheme
+	if options.highlight {
+		generator.Highlight = gen.HighlightCode
+	}
+	if options.template != "" {
+		tmpl, err := template.ParseFiles(options.template)
+		if err != nil {
+			return err
+		}
+		generator.Template = tmpl
+	}
+	if err := generator.GenerateHtml(outputPath); err != nil {
+		return err
+	}
+
+	if title := generator.Title(); title != "" {
+		fmt.Printf("Finished converting \"%s\" to HTML\n", title)
+	} else {
+		fmt.Printf("Finished converting Markdown to HTML\n")
+	}
+	fmt.Printf("Output at %s\n", outputPath)
+
+	return nil
+}
+
+func convertToMarkdown(path string, outputPath string, extensions parse.Extension, options markdownOptions) error {
+	document, err := readDocument(path, extensions, options.transformers)
+	if err != nil {
+		return err
+	}
+
+	generator := options.markdownGenerator(document)
+	if err := generator.GenerateMarkdown(outputPath); err != nil {
+		return err
+	}
+
+	fmt.Printf("Finished converting to Markdown\n")
+	fmt.Printf("Output at %s\n", outputPath)
+
+	return nil
+}
 
-	gen := gen.NewGenerator(exprs)
-	gen.GenerateHtml(outputPath)
+func convertToText(path string, outputPath string, extensions parse.Extension, transformers []parse.Transformer, linkUrls bool) error {
+	document, err := readDocument(path, extensions, transformers)
+	if err != nil {
+		return err
+	}
+
+	generator := gen.NewTextGenerator(document)
+	generator.LinkUrls = linkUrls
+	if err := generator.GenerateText(outputPath); err != nil {
+		return err
+	}
+
+	fmt.Printf("Finished converting Markdown to text\n")
+	fmt.Printf("Output at %s\n", outputPath)
+
+	return nil
+}
+
+func convertToTerminal(path string, outputPath string, extensions parse.Extension, transformers []parse.Transformer, width int, linkUrls bool) error {
+	document, err := readDocument(path, extensions, transformers)
+	if err != nil {
+		return err
+	}
+
+	generator := gen.NewTerminalGenerator(document)
+	generator.Width = width
+	if width == 0 {
+		generator.Width = outputWidth()
+	}
+	generator.Hyperlinks = !linkUrls
+	if err := generator.GenerateTerminal(outputPath); err != nil {
+		return err
+	}
 
-	fmt.Printf("Finished converting Markdown to HTML")
-	fmt.Printf("Output at %s", outputPath)
+	fmt.Printf("Finished converting Markdown to terminal text\n")
+	fmt.Printf("Output at %s\n", outputPath)
 
 	return nil
 }
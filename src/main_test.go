@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestMain runs the command line rather than the tests when runAllium
+// starts the test binary, so that commands can be checked by exit code.
+func TestMain(m *testing.M) {
+	if os.Getenv("ALLIUM_RUN_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// runAllium runs the command line with args and input on standard input,
+// returning its standard output and exit code.
+func runAllium(t *testing.T, input string, args ...string) (string, int) {
+	t.Helper()
+
+	command := exec.Command(os.Args[0], args...)
+	command.Env = append(os.Environ(), "ALLIUM_RUN_MAIN=1")
+	command.Stdin = strings.NewReader(input)
+	var stdout bytes.Buffer
+	command.Stdout = &stdout
+
+	err := command.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return stdout.String(), exitErr.ExitCode()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return stdout.String(), 0
+}
@@ -0,0 +1,834 @@
+package parse
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type blockKind int
+
+const (
+	documentBlock blockKind = iota
+	blockQuoteBlock
+	listBlock
+	listItemBlock
+	paragraphBlock
+	headerBlock
+	horizontalRuleBlock
+	codeBlock
+	htmlBlock
+	tableBlock
+	footnoteDefinitionBlock
+	definitionListBlock
+	termBlock
+	definitionBlock
+)
+
+// block is an entry in the container tree built by the block phase. Blocks
+// stay open while following lines continue them and are finalized once a
+// line fails to match, after which they are turned into nodes.
+type block struct {
+	kind     blockKind
+	parent   *block
+	children []*block
+	open     bool
+	content  strings.Builder
+
+	startLine   int
+	startColumn int
+	endLine     int
+	endColumn   int
+
+	level    int
+	isSetext bool
+
+	list *listData
+
+	isTight bool
+
+	isFenced    bool
+	fenceChar   byte
+	fenceLength int
+	fenceOffset int
+	info        string
+	literal     string
+
+	htmlBlockType int
+
+	table *tableData
+	label string
+
+	source sourceMap
+}
+
+// sourceLine records where a line of block content starts, both in the
+// source and in the content.
+type sourceLine struct {
+	line   int
+	column int
+	offset int
+}
+
+// sourceMap locates offsets into the content of a block in the source.
+// trimmed counts the bytes removed from the front of the content since its
+// lines were added.
+type sourceMap struct {
+	lines   []sourceLine
+	trimmed int
+}
+
+func (m sourceMap) position(offset int) Position {
+	offset += m.trimmed
+
+	// lines are in order of offset, so the line holding offset is the one
+	// before the first that starts after it
+	i := sort.Search(len(m.lines), func(i int) bool { return m.lines[i].offset > offset })
+	if i == 0 {
+		return Position{}
+	}
+	line := m.lines[i-1]
+
+	return Position{Line: line.line, Column: line.column + offset - line.offset}
+}
+
+// span covers the content from start up to but not including end.
+func (m sourceMap) span(start int, end int) Span {
+	return Span{Start: m.position(start), End: m.position(max(start, end-1))}
+}
+
+func (b *block) span() Span {
+	return Span{
+		Start: Position{Line: b.startLine, Column: b.startColumn},
+		End:   Position{Line: b.endLine, Column: b.endColumn},
+	}
+}
+
+type listData struct {
+	isOrdered    bool
+	isTight      bool
+	bulletChar   byte
+	start        int
+	delimiter    byte
+	padding      int
+	markerOffset int
+}
+
+const (
+	continueMatched = iota
+	continueUnmatched
+	continueDone
+)
+
+const (
+	startNone = iota
+	startContainer
+	startLeaf
+)
+
+func newBlock(kind blockKind, line int, column int) *block {
+	var b block
+	b.kind = kind
+	b.open = true
+	b.startLine = line
+	b.startColumn = column
+
+	return &b
+}
+
+func (b *block) appendChild(child *block) {
+	child.parent = b
+	b.children = append(b.children, child)
+}
+
+func (b *block) lastChild() *block {
+	if len(b.children) == 0 {
+		return nil
+	}
+	return b.children[len(b.children)-1]
+}
+
+func (b *block) removeChild(child *block) {
+	for i, c := range b.children {
+		if c == child {
+			b.children = append(b.children[:i], b.children[i+1:]...)
+			return
+		}
+	}
+}
+
+func (b *block) canContain(kind blockKind) bool {
+	switch b.kind {
+	case documentBlock, blockQuoteBlock, listItemBlock, footnoteDefinitionBlock, definitionBlock:
+		return kind != listItemBlock
+	case listBlock:
+		return kind == listItemBlock
+	case definitionListBlock:
+		return kind == termBlock || kind == definitionBlock
+	default:
+		return false
+	}
+}
+
+func (b *block) acceptsLines() bool {
+	return b.kind == paragraphBlock || b.kind == codeBlock || b.kind == htmlBlock || b.kind == tableBlock
+}
+
+func (p *Parser) continueBlock(b *block) int {
+	switch b.kind {
+	case documentBlock, listBlock, definitionListBlock:
+		return continueMatched
+	case blockQuoteBlock:
+		if p.indented || peek(p.currentLine, p.nextNonspace) != '>' {
+			return continueUnmatched
+		}
+
+		p.advanceNextNonspace()
+		p.advanceOffset(1, false)
+		if isSpaceOrTab(peek(p.currentLine, p.offset)) {
+			p.advanceOffset(1, true)
+		}
+		return continueMatched
+	case listItemBlock, definitionBlock:
+		if p.blank {
+			// a blank line after an empty list item ends it
+			if len(b.children) == 0 {
+				return continueUnmatched
+			}
+			p.advanceNextNonspace()
+		} else if p.indent >= b.list.markerOffset+b.list.padding {
+			p.advanceOffset(b.list.markerOffset+b.list.padding, true)
+		} else {
+			return continueUnmatched
+		}
+		return continueMatched
+	case codeBlock:
+		return p.continueCodeBlock(b)
+	case footnoteDefinitionBlock:
+		return p.continueFootnoteDefinition(b)
+	case htmlBlock:
+		if p.blank && (b.htmlBlockType == 6 || b.htmlBlockType == 7) {
+			return continueUnmatched
+		}
+		return continueMatched
+	case paragraphBlock, tableBlock:
+		if p.blank {
+			return continueUnmatched
+		}
+		return continueMatched
+	default:
+		return continueUnmatched
+	}
+}
+
+var closingCodeFence = regexp.MustCompile("^(?:`{3,}|~{3,})[ \t]*$")
+
+func (p *Parser) continueCodeBlock(b *block) int {
+	if !b.isFenced {
+		if p.indent >= codeIndent {
+			p.advanceOffset(codeIndent, true)
+		} else if p.blank {
+			p.advanceNextNonspace()
+		} else {
+			return continueUnmatched
+		}
+		return continueMatched
+	}
+
+	rest := p.currentLine[p.nextNonspace:]
+	if p.indent <= 3 && peek(p.currentLine, p.nextNonspace) == b.fenceChar && closingCodeFence.MatchString(rest) {
+		fence := strings.TrimRight(rest, " \t")
+		if len(fence) >= b.fenceLength {
+			p.lastLineLength = len(p.currentLine)
+			p.finalize(b, p.lineNumber)
+			return continueDone
+		}
+	}
+
+	// skip the indentation the opening fence had
+	for i := b.fenceOffset; i > 0 && isSpaceOrTab(peek(p.currentLine, p.offset)); i-- {
+		p.advanceOffset(1, true)
+	}
+	return continueMatched
+}
+
+func (p *Parser) finalizeBlock(b *block) {
+	switch b.kind {
+	case paragraphBlock:
+		content := b.content.String()
+		hasReferences := false
+		for strings.HasPrefix(content, "[") {
+			length := parseReference(content, p.references)
+			if length == 0 {
+				break
+			}
+			content = content[length:]
+			hasReferences = true
+		}
+
+		b.source.trimmed += b.content.Len() - len(content)
+		b.content.Reset()
+		b.content.WriteString(content)
+		if hasReferences && isBlank(content) {
+			b.parent.removeChild(b)
+		}
+	case codeBlock:
+		content := b.content.String()
+		if b.isFenced {
+			firstLine, rest, _ := strings.Cut(content, "\n")
+			b.info = unescapeString(strings.TrimSpace(firstLine))
+			b.literal = rest
+
+			// without a closing fence the block ends with its last
+			// line that is not blank, or failing that its opening fence
+			lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+			if last := len(b.source.lines) - 1; b.endLine <= b.source.lines[last].line {
+				for last > 0 && isBlank(lines[last]) {
+					last--
+				}
+				b.endLine = b.source.lines[last].line
+				b.endColumn = b.source.lines[last].column + len(lines[last]) - 1
+			}
+		} else {
+			lines := strings.Split(content, "\n")
+			for len(lines) > 0 && isBlank(lines[len(lines)-1]) {
+				lines = lines[:len(lines)-1]
+			}
+			b.literal = strings.Join(lines, "\n") + "\n"
+
+			// the block ends with its last line of code, not the blank
+			// lines that closed it
+			last := b.source.lines[len(lines)-1]
+			b.endLine = last.line
+			b.endColumn = last.column + len(lines[len(lines)-1]) - 1
+		}
+	case htmlBlock:
+		b.literal = strings.TrimSuffix(b.content.String(), "\n")
+	case documentBlock:
+		b.endAtLastChild()
+	case listItemBlock, definitionBlock:
+		if b.lastChild() != nil {
+			b.endAtLastChild()
+		} else {
+			b.endLine = b.startLine
+			b.endColumn = b.list.markerOffset + b.list.padding
+		}
+	case listBlock:
+		b.endAtLastChild()
+		b.list.isTight = isTightList(b)
+	case footnoteDefinitionBlock:
+		b.endAtLastChild()
+		p.footnotes.define(b)
+	case definitionListBlock:
+		b.endAtLastChild()
+		markTightDefinitions(b)
+	}
+}
+
+// endAtLastChild ends a container where its last child ends, rather than on
+// the line that closed it, which may be a blank line after it.
+func (b *block) endAtLastChild() {
+	if last := b.lastChild(); last != nil {
+		b.endLine = last.endLine
+		b.endColumn = last.endColumn
+	}
+}
+
+// isTightList reports whether no items of the list, and no blocks directly
+// inside an item, are separated by blank lines.
+func isTightList(list *block) bool {
+	for i, item := range list.children {
+		if i < len(list.children)-1 && endsWithBlankLine(item, list.children[i+1]) {
+			return false
+		}
+
+		for j, child := range item.children {
+			if j < len(item.children)-1 && endsWithBlankLine(child, item.children[j+1]) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func endsWithBlankLine(b *block, next *block) bool {
+	return b.endLine != next.startLine-1
+}
+
+type blockStart func(p *Parser, container *block) int
+
+// blockStarts are tried in order against the rest of a line once its open
+// blocks have been matched.
+var blockStarts = []blockStart{
+	startFootnoteDefinition,
+	startBlockQuote,
+	startAtxHeader,
+	startFencedCode,
+	startHtmlBlock,
+	startTable,
+	startDefinition,
+	startSetextHeader,
+	startHorizontalRule,
+	startListItem,
+	startIndentedCode,
+}
+
+func startBlockQuote(p *Parser, container *block) int {
+	if p.indented || peek(p.currentLine, p.nextNonspace) != '>' {
+		return startNone
+	}
+
+	p.advanceNextNonspace()
+	p.advanceOffset(1, false)
+	if isSpaceOrTab(peek(p.currentLine, p.offset)) {
+		p.advanceOffset(1, true)
+	}
+
+	p.closeUnmatchedBlocks()
+	p.addChild(blockQuoteBlock, p.nextNonspace)
+
+	return startContainer
+}
+
+var (
+	atxHeaderMarker       = regexp.MustCompile(`^#{1,6}(?:[ \t]+|$)`)
+	atxHeaderClosingEmpty = regexp.MustCompile(`^[ \t]*#+[ \t]*$`)
+	atxHeaderClosing      = regexp.MustCompile(`[ \t]+#+[ \t]*$`)
+)
+
+func startAtxHeader(p *Parser, container *block) int {
+	if p.indented {
+		return startNone
+	}
+
+	marker := atxHeaderMarker.FindString(p.currentLine[p.nextNonspace:])
+	if marker == "" {
+		return startNone
+	}
+
+	p.advanceNextNonspace()
+	p.advanceOffset(len(marker), false)
+	p.closeUnmatchedBlocks()
+
+	header := p.addChild(headerBlock, p.nextNonspace)
+	header.level = len(strings.TrimSpace(marker))
+
+	header.source.lines = []sourceLine{{line: p.lineNumber, column: p.offset + 1}}
+
+	content := p.currentLine[p.offset:]
+	content = atxHeaderClosingEmpty.ReplaceAllString(content, "")
+	content = atxHeaderClosing.ReplaceAllString(content, "")
+	header.content.WriteString(content)
+	p.advanceOffset(len(p.currentLine)-p.offset, false)
+
+	return startLeaf
+}
+
+var codeFence = regexp.MustCompile("^(?:`{3,}|~{3,})")
+
+func startFencedCode(p *Parser, container *block) int {
+	if p.indented {
+		return startNone
+	}
+
+	rest := p.currentLine[p.nextNonspace:]
+	fence := codeFence.FindString(rest)
+	if fence == "" {
+		return startNone
+	}
+
+	// backtick fences cannot have backticks in their info string
+	if fence[0] == '`' && strings.Contains(rest[len(fence):], "`") {
+		return startNone
+	}
+
+	p.closeUnmatchedBlocks()
+
+	code := p.addChild(codeBlock, p.nextNonspace)
+	code.isFenced = true
+	code.fenceChar = fence[0]
+	code.fenceLength = len(fence)
+	code.fenceOffset = p.indent
+
+	p.advanceNextNonspace()
+	p.advanceOffset(len(fence), false)
+
+	return startLeaf
+}
+
+const (
+	tagName             = `[A-Za-z][A-Za-z0-9-]*`
+	attributeName       = `[a-zA-Z_:][a-zA-Z0-9_.:-]*`
+	unquotedValue       = "[^\"'=<>`\\x00-\\x20]+"
+	singleQuotedValue   = `'[^']*'`
+	doubleQuotedValue   = `"[^"]*"`
+	attributeValue      = `(?:` + unquotedValue + `|` + singleQuotedValue + `|` + doubleQuotedValue + `)`
+	attributeValueSpec  = `(?:\s*=\s*` + attributeValue + `)`
+	attribute           = `(?:\s+` + attributeName + attributeValueSpec + `?)`
+	openTag             = `<` + tagName + attribute + `*\s*/?>`
+	closeTag            = `</` + tagName + `\s*[>]`
+	htmlComment         = `<!-->|<!--->|<!--[\s\S]*?-->`
+	processingInstr     = `[<][?][\s\S]*?[?][>]`
+	declaration         = `<![A-Za-z]+[^>]*>`
+	cdata               = `<!\[CDATA\[[\s\S]*?\]\]>`
+	htmlTag             = `(?:` + openTag + `|` + closeTag + `|` + htmlComment + `|` + processingInstr + `|` + declaration + `|` + cdata + `)`
+	htmlBlockTagNames   = `address|article|aside|base|basefont|blockquote|body|caption|center|col|colgroup|dd|details|dialog|dir|div|dl|dt|fieldset|figcaption|figure|footer|form|frame|frameset|h[123456]|head|header|hr|html|iframe|legend|li|link|main|menu|menuitem|nav|noframes|ol|optgroup|option|p|param|search|section|summary|table|tbody|td|tfoot|th|thead|title|tr|track|ul`
+	htmlBlockOpenSimple = `(?i)^<(?:script|pre|textarea|style)(?:\s|>|$)`
+)
+
+var htmlBlockOpen = []*regexp.Regexp{
+	nil,
+	regexp.MustCompile(htmlBlockOpenSimple),
+	regexp.MustCompile(`^<!--`),
+	regexp.MustCompile(`^<[?]`),
+	regexp.MustCompile(`^<![A-Za-z]`),
+	regexp.MustCompile(`^<!\[CDATA\[`),
+	regexp.MustCompile(`(?i)^<[/]?(?:` + htmlBlockTagNames + `)(?:\s|[/]?[>]|$)`),
+	regexp.MustCompile(`(?i)^(?:` + openTag + `|` + closeTag + `)\s*$`),
+}
+
+var htmlBlockClose = []*regexp.Regexp{
+	nil,
+	regexp.MustCompile(`(?i)</(?:script|pre|textarea|style)>`),
+	regexp.MustCompile(`-->`),
+	regexp.MustCompile(`\?>`),
+	regexp.MustCompile(`>`),
+	regexp.MustCompile(`\]\]>`),
+}
+
+func startHtmlBlock(p *Parser, container *block) int {
+	if p.indented || peek(p.currentLine, p.nextNonspace) != '<' {
+		return startNone
+	}
+
+	rest := p.currentLine[p.nextNonspace:]
+	for blockType := 1; blockType <= 7; blockType++ {
+		if !htmlBlockOpen[blockType].MatchString(rest) {
+			continue
+		}
+
+		// type 7 blocks cannot interrupt a paragraph, lazy or not
+		lazyParagraph := !p.allClosed && !p.blank && p.tip.kind == paragraphBlock
+		if blockType == 7 && (container.kind == paragraphBlock || lazyParagraph) {
+			continue
+		}
+
+		p.closeUnmatchedBlocks()
+
+		html := p.addChild(htmlBlock, p.offset)
+		html.htmlBlockType = blockType
+
+		return startLeaf
+	}
+
+	return startNone
+}
+
+var headerId = regexp.MustCompile(`[ \t]*\{#([A-Za-z0-9_:.-]+)\}[ \t]*$`)
+
+// parseHeaderId strips a trailing "{#custom-id}" attribute from the header
+// text and returns the text along with the ID.
+func parseHeaderId(content string) (string, string) {
+	content = strings.TrimRight(content, " \t\n")
+
+	match := headerId.FindStringSubmatchIndex(content)
+	if match == nil {
+		return content, ""
+	}
+
+	return content[:match[0]], content[match[2]:match[3]]
+}
+
+var setextHeaderLine = regexp.MustCompile(`^(?:=+|-+)[ \t]*$`)
+
+func startSetextHeader(p *Parser, container *block) int {
+	if p.indented || container.kind != paragraphBlock {
+		return startNone
+	}
+
+	line := p.currentLine[p.nextNonspace:]
+	if !setextHeaderLine.MatchString(line) {
+		return startNone
+	}
+
+	p.closeUnmatchedBlocks()
+
+	// link reference definitions at the start of the paragraph are not
+	// part of the heading text
+	content := container.content.String()
+	for strings.HasPrefix(content, "[") {
+		length := parseReference(content, p.references)
+		if length == 0 {
+			break
+		}
+		content = content[length:]
+	}
+
+	if content == "" {
+		return startNone
+	}
+
+	header := newBlock(headerBlock, container.startLine, container.startColumn)
+	header.level = 1
+	if line[0] == '-' {
+		header.level = 2
+	}
+	header.isSetext = true
+	header.content.WriteString(content)
+	header.source = container.source
+	header.source.trimmed += container.content.Len() - len(content)
+
+	parent := container.parent
+	for i, child := range parent.children {
+		if child == container {
+			parent.children[i] = header
+			header.parent = parent
+		}
+	}
+
+	p.tip = header
+	p.advanceOffset(len(p.currentLine)-p.offset, false)
+
+	return startLeaf
+}
+
+var horizontalRule = regexp.MustCompile(`^(?:\*[ \t]*){3,}$|^(?:_[ \t]*){3,}$|^(?:-[ \t]*){3,}$`)
+
+func startHorizontalRule(p *Parser, container *block) int {
+	if p.indented || !horizontalRule.MatchString(p.currentLine[p.nextNonspace:]) {
+		return startNone
+	}
+
+	p.closeUnmatchedBlocks()
+	p.addChild(horizontalRuleBlock, p.nextNonspace)
+	p.advanceOffset(len(p.currentLine)-p.offset, false)
+
+	return startLeaf
+}
+
+func startListItem(p *Parser, container *block) int {
+	if p.indented && container.kind != listBlock {
+		return startNone
+	}
+
+	data := p.parseListMarker(container)
+	if data == nil {
+		return startNone
+	}
+
+	p.closeUnmatchedBlocks()
+
+	if p.tip.kind != listBlock || !listsMatch(p.tip.list, data) {
+		list := p.addChild(listBlock, p.nextNonspace)
+		list.list = data
+	}
+
+	item := p.addChild(listItemBlock, p.nextNonspace)
+	item.list = data
+
+	return startContainer
+}
+
+var (
+	bulletListMarker  = regexp.MustCompile(`^[*+-]`)
+	orderedListMarker = regexp.MustCompile(`^(\d{1,9})([.)])`)
+)
+
+func (p *Parser) parseListMarker(container *block) *listData {
+	if p.indent >= codeIndent {
+		return nil
+	}
+
+	rest := p.currentLine[p.nextNonspace:]
+	data := listData{isTight: true, markerOffset: p.indent}
+
+	var marker string
+	if match := bulletListMarker.FindString(rest); match != "" {
+		marker = match
+		data.bulletChar = match[0]
+	} else if match := orderedListMarker.FindStringSubmatch(rest); match != nil &&
+		(container.kind != paragraphBlock || match[1] == "1") {
+		marker = match[0]
+		data.isOrdered = true
+		data.start, _ = strconv.Atoi(match[1])
+		data.delimiter = match[2][0]
+	} else {
+		return nil
+	}
+
+	// the marker has to be followed by whitespace or the end of the line
+	next := peek(p.currentLine, p.nextNonspace+len(marker))
+	if next != 0 && !isSpaceOrTab(next) {
+		return nil
+	}
+
+	// an empty list item cannot interrupt a paragraph
+	if container.kind == paragraphBlock && isBlank(p.currentLine[p.nextNonspace+len(marker):]) {
+		return nil
+	}
+
+	p.advanceNextNonspace()
+	p.advanceOffset(len(marker), true)
+	spacesStartColumn := p.column
+	spacesStartOffset := p.offset
+
+	for {
+		p.advanceOffset(1, true)
+		if p.column-spacesStartColumn >= 5 || !isSpaceOrTab(peek(p.currentLine, p.offset)) {
+			break
+		}
+	}
+
+	blankItem := p.offset >= len(p.currentLine)
+	spacesAfterMarker := p.column - spacesStartColumn
+
+	// with five or more spaces the content is an indented code block, so
+	// only the first space belongs to the marker
+	if spacesAfterMarker >= 5 || spacesAfterMarker < 1 || blankItem {
+		data.padding = len(marker) + 1
+		p.column = spacesStartColumn
+		p.offset = spacesStartOffset
+		if isSpaceOrTab(peek(p.currentLine, p.offset)) {
+			p.advanceOffset(1, true)
+		}
+	} else {
+		data.padding = len(marker) + spacesAfterMarker
+	}
+
+	return &data
+}
+
+func listsMatch(a *listData, b *listData) bool {
+	return a.isOrdered == b.isOrdered && a.delimiter == b.delimiter && a.bulletChar == b.bulletChar
+}
+
+func startIndentedCode(p *Parser, container *block) int {
+	if !p.indented || p.tip.kind == paragraphBlock || p.blank {
+		return startNone
+	}
+
+	p.advanceOffset(codeIndent, true)
+	p.closeUnmatchedBlocks()
+	p.addChild(codeBlock, p.offset)
+
+	return startLeaf
+}
+
+func peek(s string, i int) byte {
+	if i < 0 || i >= len(s) {
+		return 0
+	}
+	return s[i]
+}
+
+func isSpaceOrTab(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+func isBlank(s string) bool {
+	return strings.TrimLeft(s, " \t\n") == ""
+}
+
+// buildNode turns a finalized block and its children into nodes, running the
+// inline phase over the raw text of paragraphs and headers.
+func (p *Parser) buildNode(b *block) Node {
+	node := p.buildBlock(b)
+	if node != nil {
+		node.base().span = b.span()
+	}
+
+	return node
+}
+
+func (p *Parser) buildBlock(b *block) Node {
+	switch b.kind {
+	case documentBlock:
+		var node DocumentNode
+		node.Nodes = p.buildChildren(b)
+		return &node
+	case blockQuoteBlock:
+		var node BlockQuoteNode
+		node.Nodes = p.buildChildren(b)
+		return &node
+	case listBlock:
+		var node ListNode
+		node.Nodes = p.buildChildren(b)
+		node.IsOrdered = b.list.isOrdered
+		node.IsTight = b.list.isTight
+		node.Start = b.list.start
+		if b.list.isOrdered {
+			node.Marker = string(b.list.delimiter)
+		} else {
+			node.Marker = string(b.list.bulletChar)
+		}
+		return &node
+	case listItemBlock:
+		var node ListItemNode
+		if p.hasExtension(TaskListExtension) {
+			node.Checked = parseTaskMarker(b)
+		}
+		node.Nodes = p.buildChildren(b)
+		return &node
+	case paragraphBlock:
+		var node ParagraphNode
+		node.Content = p.parseInlines(b.content.String(), b.source)
+		return &node
+	case headerBlock:
+		var node HeaderNode
+		node.Level = b.level
+		node.IsSetext = b.isSetext
+
+		content := b.content.String()
+		if p.hasExtension(HeaderIdsExtension) {
+			content, node.ID = parseHeaderId(content)
+		}
+		node.Content = p.parseInlines(content, b.source)
+		return &node
+	case horizontalRuleBlock:
+		return &HorizontalRuleNode{}
+	case codeBlock:
+		var node InlineCodeBlockNode
+		node.Content = b.literal
+		node.Info = b.info
+		node.IsFenced = b.isFenced
+		return &node
+	case htmlBlock:
+		var node HtmlBlockNode
+		node.Content = b.literal
+		return &node
+	case tableBlock:
+		return p.buildTable(b)
+	case definitionListBlock:
+		var node DefinitionListNode
+		node.Nodes = p.buildChildren(b)
+		return &node
+	case termBlock:
+		var node TermNode
+		node.Content = p.parseInlines(b.content.String(), b.source)
+		return &node
+	case definitionBlock:
+		var node DefinitionNode
+		node.Nodes = p.buildChildren(b)
+		node.IsTight = b.isTight
+		return &node
+	default:
+		return nil
+	}
+}
+
+func (p *Parser) buildChildren(b *block) []Node {
+	var nodes []Node
+	for _, child := range b.children {
+		// footnote definitions are collected on the document instead
+		if child.kind == footnoteDefinitionBlock {
+			p.buildFootnoteDefinition(child)
+			continue
+		}
+
+		nodes = append(nodes, p.buildNode(child))
+	}
+
+	return nodes
+}
@@ -0,0 +1,115 @@
+package parse
+
+import "strings"
+
+// startDefinition opens a definition on a line starting with ":". The
+// terms are the lines of the paragraph right above it, which may be
+// separated from it by a single blank line, or the definition continues
+// the list the previous definition belongs to.
+func startDefinition(p *Parser, container *block) int {
+	if !p.hasExtension(DefinitionListExtension) || p.indented {
+		return startNone
+	}
+
+	if peek(p.currentLine, p.nextNonspace) != ':' || !isSpaceOrTab(peek(p.currentLine, p.nextNonspace+1)) {
+		return startNone
+	}
+
+	// a paragraph closed by one blank line still gives the terms, of a
+	// definition that is then loose
+	terms := container
+	if last := container.lastChild(); container.kind != paragraphBlock && last != nil &&
+		last.kind == paragraphBlock && !last.open && last.endLine == p.lineNumber-2 {
+		terms = last
+	}
+
+	if terms.kind != paragraphBlock && container.kind != definitionListBlock {
+		return startNone
+	}
+
+	p.closeUnmatchedBlocks()
+
+	if terms.kind == paragraphBlock && !p.addTerms(terms) {
+		return startNone
+	}
+
+	markerOffset := p.indent
+	p.advanceNextNonspace()
+	p.advanceOffset(1, true)
+
+	spacesStartColumn := p.column
+	for p.column-spacesStartColumn < 4 && isSpaceOrTab(peek(p.currentLine, p.offset)) {
+		p.advanceOffset(1, true)
+	}
+
+	definition := p.addChild(definitionBlock, p.nextNonspace)
+	definition.list = &listData{markerOffset: markerOffset, padding: 1 + p.column - spacesStartColumn}
+
+	return startContainer
+}
+
+// addTerms replaces a paragraph with one term per line, appended to the
+// definition list right before it if there is one. Link reference
+// definitions at the start of the paragraph are not terms, and false is
+// returned when there is nothing else.
+func (p *Parser) addTerms(paragraph *block) bool {
+	content := paragraph.content.String()
+	for strings.HasPrefix(content, "[") {
+		length := parseReference(content, p.references)
+		if length == 0 {
+			break
+		}
+		content = content[length:]
+	}
+
+	if isBlank(content) {
+		return false
+	}
+
+	parent := paragraph.parent
+	parent.removeChild(paragraph)
+
+	// the terms are the last lines added to the paragraph
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	sources := paragraph.source.lines[len(paragraph.source.lines)-len(lines):]
+
+	list := parent.lastChild()
+	if list != nil && list.kind == definitionListBlock {
+		list.open = true
+	} else {
+		list = newBlock(definitionListBlock, sources[0].line, sources[0].column)
+		parent.appendChild(list)
+	}
+
+	for i, line := range lines {
+		source := sources[i]
+
+		term := newBlock(termBlock, source.line, source.column)
+		term.open = false
+		term.endLine = term.startLine
+		term.endColumn = source.column + len(line) - 1
+		term.content.WriteString(line)
+		term.source.lines = []sourceLine{{line: source.line, column: source.column}}
+		list.appendChild(term)
+	}
+
+	p.tip = list
+	return true
+}
+
+// markTightDefinitions marks a definition as tight unless a blank line
+// precedes it or separates the blocks inside it, like PHP Markdown Extra.
+func markTightDefinitions(list *block) {
+	for i, child := range list.children {
+		if child.kind != definitionBlock {
+			continue
+		}
+
+		child.isTight = i == 0 || !endsWithBlankLine(list.children[i-1], child)
+		for j := 0; j < len(child.children)-1; j++ {
+			if endsWithBlankLine(child.children[j], child.children[j+1]) {
+				child.isTight = false
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package parse_test
+
+import (
+	"allium/src/parse"
+	"testing"
+)
+
+func TestDefinitionLists(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		html     string
+	}{
+		{
+			name:     "several terms and tight and loose definitions",
+			markdown: "Term 1\nTerm 2\n: def a\n\n: def b\n\n    more\n\nTerm 3\n: tight\n",
+			html: "<dl>\n<dt>Term 1</dt>\n<dt>Term 2</dt>\n<dd>def a</dd>\n<dd>\n<p>def b</p>\n<p>more</p>\n</dd>\n" +
+				"<dt>Term 3</dt>\n<dd>tight</dd>\n</dl>\n",
+		},
+		{
+			name:     "several definitions of a term",
+			markdown: "Term\n: one\n: two\n",
+			html:     "<dl>\n<dt>Term</dt>\n<dd>one</dd>\n<dd>two</dd>\n</dl>\n",
+		},
+		{
+			name:     "inline content",
+			markdown: "*Term*\n:   `code`\n",
+			html:     "<dl>\n<dt><em>Term</em></dt>\n<dd><code>code</code></dd>\n</dl>\n",
+		},
+		{
+			name:     "blank line after the term",
+			markdown: "Term\n\n: def\n",
+			html:     "<dl>\n<dt>Term</dt>\n<dd>\n<p>def</p>\n</dd>\n</dl>\n",
+		},
+		{
+			name:     "blank line after the term of a later definition",
+			markdown: "A\n: a\n\nB\n\n: b\n",
+			html:     "<dl>\n<dt>A</dt>\n<dd>a</dd>\n<dt>B</dt>\n<dd>\n<p>b</p>\n</dd>\n</dl>\n",
+		},
+		{
+			name:     "two blank lines after the term",
+			markdown: "Term\n\n\n: def\n",
+			html:     "<p>Term</p>\n<p>: def</p>\n",
+		},
+		{
+			name:     "link reference definition before the term",
+			markdown: "[foo]: /url\nTerm [foo]\n: def\n",
+			html:     "<dl>\n<dt>Term <a href=\"/url\">foo</a></dt>\n<dd>def</dd>\n</dl>\n",
+		},
+		{
+			name:     "only link reference definitions",
+			markdown: "[foo]: /url\n: def\n",
+			html:     "<p>: def</p>\n",
+		},
+		{
+			name:     "no term",
+			markdown: ": no term\n",
+			html:     "<p>: no term</p>\n",
+		},
+	}
+
+	for _, test := range tests {
+		if html := render(test.markdown, parse.DefinitionListExtension); html != test.html {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.name, html, test.html)
+		}
+	}
+
+	if html := render("Term\n: def\n", 0); html != "<p>Term\n: def</p>\n" {
+		t.Errorf("without the extension: got\n%s", html)
+	}
+}
@@ -0,0 +1,54 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Extension is a set of opt-in syntax extensions on top of CommonMark.
+type Extension int
+
+const (
+	TablesExtension Extension = 1 << iota
+	StrikethroughExtension
+	TaskListExtension
+	FootnotesExtension
+	DefinitionListExtension
+	FrontMatterExtension
+	HeaderIdsExtension
+)
+
+var extensionNames = map[string]Extension{
+	"tables":        TablesExtension,
+	"strikethrough": StrikethroughExtension,
+	"tasklist":      TaskListExtension,
+	"footnotes":     FootnotesExtension,
+	"deflist":       DefinitionListExtension,
+	"frontmatter":   FrontMatterExtension,
+	"headerids":     HeaderIdsExtension,
+}
+
+// ParseExtensions reads a comma separated list of extension names, as given
+// on the command line, into a set of extensions.
+func ParseExtensions(names string) (Extension, error) {
+	var extensions Extension
+
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		extension, ok := extensionNames[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown extension: %s", name)
+		}
+		extensions |= extension
+	}
+
+	return extensions, nil
+}
+
+func (p *Parser) hasExtension(extension Extension) bool {
+	return p.Extensions&extension != 0
+}
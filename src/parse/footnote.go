@@ -0,0 +1,165 @@
+package parse
+
+import (
+	"regexp"
+	"sort"
+)
+
+type footnote struct {
+	block      *block
+	node       *FootnoteDefinitionNode
+	index      int
+	references int
+}
+
+// footnoteIndex keeps every footnote definition by its normalised label.
+// Footnotes are numbered in the order they are first referenced.
+type footnoteIndex struct {
+	definitions map[string]*footnote
+	count       int
+}
+
+func newFootnoteIndex() *footnoteIndex {
+	var index footnoteIndex
+	index.definitions = make(map[string]*footnote)
+
+	return &index
+}
+
+var footnoteDefinitionMarker = regexp.MustCompile(`^\[\^([^\]\s]+)\]:`)
+
+func startFootnoteDefinition(p *Parser, container *block) int {
+	if !p.hasExtension(FootnotesExtension) || p.indented {
+		return startNone
+	}
+
+	match := footnoteDefinitionMarker.FindStringSubmatch(p.currentLine[p.nextNonspace:])
+	if match == nil {
+		return startNone
+	}
+
+	p.advanceNextNonspace()
+	p.advanceOffset(len(match[0]), false)
+
+	// the content starts after the whitespace following the marker, so
+	// that it is not read as part of an HTML block or indented code
+	p.findNextNonspace()
+	p.advanceNextNonspace()
+	p.closeUnmatchedBlocks()
+
+	definition := p.addChild(footnoteDefinitionBlock, p.nextNonspace)
+	definition.label = match[1]
+
+	return startContainer
+}
+
+func (p *Parser) continueFootnoteDefinition(b *block) int {
+	if p.blank {
+		if len(b.children) == 0 {
+			return continueUnmatched
+		}
+		p.advanceNextNonspace()
+	} else if p.indent >= codeIndent {
+		p.advanceOffset(codeIndent, true)
+	} else {
+		return continueUnmatched
+	}
+
+	return continueMatched
+}
+
+// define registers a finalized definition so references anywhere in the
+// document can be resolved during the inline phase. The first definition
+// of a label wins.
+func (index *footnoteIndex) define(b *block) {
+	label := normaliseFootnoteLabel(b.label)
+	if _, ok := index.definitions[label]; ok {
+		return
+	}
+
+	var node FootnoteDefinitionNode
+	node.Label = b.label
+	index.definitions[label] = &footnote{block: b, node: &node}
+}
+
+func (p *Parser) buildFootnoteDefinition(b *block) {
+	note := p.footnotes.definitions[normaliseFootnoteLabel(b.label)]
+	if note.block == b {
+		note.node.Nodes = p.buildChildren(b)
+		note.node.span = b.span()
+	}
+}
+
+func normaliseFootnoteLabel(label string) string {
+	return normaliseReference("[" + label + "]")
+}
+
+// reference resolves a reference to the footnote with the given
+// label, numbering the footnote on its first reference.
+func (index *footnoteIndex) reference(label string) (*FootnoteReferenceNode, bool) {
+	note, ok := index.definitions[normaliseFootnoteLabel(label)]
+	if !ok {
+		return nil, false
+	}
+
+	if note.index == 0 {
+		index.count++
+		note.index = index.count
+	}
+	note.references++
+
+	var node FootnoteReferenceNode
+	node.Label = label
+	node.Index = note.index
+	node.Occurrence = note.references
+
+	return &node, true
+}
+
+// sorted returns the definitions numbered by first reference, followed by
+// the unreferenced ones in label order.
+func (index *footnoteIndex) sorted() []*FootnoteDefinitionNode {
+	var notes []*footnote
+	for _, note := range index.definitions {
+		note.node.Index = note.index
+		note.node.References = note.references
+		notes = append(notes, note)
+	}
+
+	sort.Slice(notes, func(i, j int) bool {
+		a, b := notes[i], notes[j]
+		if (a.index == 0) != (b.index == 0) {
+			return a.index != 0
+		}
+		if a.index != b.index {
+			return a.index < b.index
+		}
+		return a.node.Label < b.node.Label
+	})
+
+	var nodes []*FootnoteDefinitionNode
+	for _, note := range notes {
+		nodes = append(nodes, note.node)
+	}
+
+	return nodes
+}
+
+var footnoteReference = regexp.MustCompile(`^\[\^([^\]\s]+)\]`)
+
+func (ip *inlineParser) parseFootnoteReference() bool {
+	match := footnoteReference.FindStringSubmatch(ip.subject[ip.pos:])
+	if match == nil {
+		return false
+	}
+
+	node, ok := ip.footnotes.reference(match[1])
+	if !ok {
+		return false
+	}
+
+	ip.pos += len(match[0])
+	ip.append(node)
+
+	return true
+}
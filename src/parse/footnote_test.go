@@ -0,0 +1,54 @@
+package parse_test
+
+import (
+	"allium/src/parse"
+	"strings"
+	"testing"
+)
+
+func TestFootnoteNumbering(t *testing.T) {
+	source := "a[^n] b[^m] c[^N] d[^x]\n\n[^m]: M\n[^n]: N\n\n    para\n\n[^u]: unused\n"
+	document := parseMarkdown(source, parse.FootnotesExtension)
+
+	// footnotes are numbered by first reference and labels ignore case
+	want := []struct {
+		label      string
+		index      int
+		references int
+	}{
+		{"n", 1, 2},
+		{"m", 2, 1},
+		{"u", 0, 0},
+	}
+	if len(document.Footnotes) != len(want) {
+		t.Fatalf("got %d footnotes, want %d", len(document.Footnotes), len(want))
+	}
+	for i, footnote := range document.Footnotes {
+		if footnote.Label != want[i].label || footnote.Index != want[i].index || footnote.References != want[i].references {
+			t.Errorf("footnote %d: got [^%s] index %d with %d references, want [^%s] index %d with %d references",
+				i, footnote.Label, footnote.Index, footnote.References, want[i].label, want[i].index, want[i].references)
+		}
+	}
+}
+
+func TestFootnoteSection(t *testing.T) {
+	html := render("a[^n] b[^m] c[^n] d[^x]\n\n[^m]: M\n[^n]: N\n\n    para\n", parse.FootnotesExtension)
+	want := "<p>a<sup class=\"footnote-ref\"><a href=\"#fn-1\" id=\"fnref-1\">1</a></sup> b<sup class=\"footnote-ref\"><a href=\"#fn-2\" id=\"fnref-2\">2</a></sup> " +
+		"c<sup class=\"footnote-ref\"><a href=\"#fn-1\" id=\"fnref-1-2\">1</a></sup> d[^x]</p>\n" +
+		"<section class=\"footnotes\">\n<ol>\n" +
+		"<li id=\"fn-1\">\n<p>N</p>\n<p>para <a href=\"#fnref-1\" class=\"footnote-backref\" aria-label=\"Back to reference 1\">↩</a> " +
+		"<a href=\"#fnref-1-2\" class=\"footnote-backref\" aria-label=\"Back to reference 1\">↩<sup>2</sup></a></p>\n</li>\n" +
+		"<li id=\"fn-2\">\n<p>M <a href=\"#fnref-2\" class=\"footnote-backref\" aria-label=\"Back to reference 2\">↩</a></p>\n</li>\n" +
+		"</ol>\n</section>\n"
+	if html != want {
+		t.Errorf("got\n%s\nwant\n%s", html, want)
+	}
+
+	if html := render("text\n\n[^1]: a note\n", parse.FootnotesExtension); html != "<p>text</p>\n" {
+		t.Errorf("unreferenced footnote: got\n%s", html)
+	}
+
+	if html := render("a[^1]\n\n[^1]: a note\n", 0); strings.Contains(html, "footnote") {
+		t.Errorf("without the extension: got\n%s", html)
+	}
+}
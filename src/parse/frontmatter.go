@@ -0,0 +1,489 @@
+package parse
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FrontMatter is the metadata block at the very start of a document,
+// fenced by "---" for YAML or "+++" for TOML. Only the commonly used subset
+// of either format is understood: scalars, lists and nested tables.
+type FrontMatter struct {
+	Format string
+	Raw    string
+	Fields map[string]any
+}
+
+// Get returns a top level field formatted as a string, or an empty string
+// when the field is missing.
+func (f *FrontMatter) Get(key string) string {
+	if f == nil {
+		return ""
+	}
+
+	value, ok := f.Fields[key]
+	if !ok || value == nil {
+		return ""
+	}
+
+	return fmt.Sprint(value)
+}
+
+// parseFrontMatter consumes a leading front matter block from the source
+// lines, returning nil when the document does not start with one.
+func (p *Parser) parseFrontMatter() *FrontMatter {
+	if len(p.lines) == 0 {
+		return nil
+	}
+
+	var format string
+	var closers []string
+	switch strings.TrimRight(p.lines[0], " \t") {
+	case "---":
+		format = "yaml"
+		closers = []string{"---", "..."}
+	case "+++":
+		format = "toml"
+		closers = []string{"+++"}
+	default:
+		return nil
+	}
+
+	for i := 1; i < len(p.lines); i++ {
+		line := strings.TrimRight(p.lines[i], " \t")
+		if line != closers[0] && (len(closers) == 1 || line != closers[1]) {
+			continue
+		}
+
+		var frontMatter FrontMatter
+		frontMatter.Format = format
+		frontMatter.Raw = strings.Join(p.lines[1:i], "\n")
+		if format == "yaml" {
+			frontMatter.Fields = parseYaml(p.lines[1:i])
+		} else {
+			frontMatter.Fields = parseToml(p.lines[1:i])
+		}
+
+		p.lineNumber = i + 1
+		return &frontMatter
+	}
+
+	return nil
+}
+
+type yamlLine struct {
+	indent int
+	text   string
+	number int
+}
+
+// yamlDocument holds the lines of a YAML block that have content, and every
+// source line for block scalars, which keep their blank and "#" lines.
+type yamlDocument struct {
+	source []string
+	lines  []yamlLine
+}
+
+func parseYaml(lines []string) map[string]any {
+	var document yamlDocument
+	document.source = lines
+	for number, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		document.lines = append(document.lines, yamlLine{indent: yamlIndent(line), text: trimmed, number: number})
+	}
+
+	fields, _ := document.parseMap(0)
+	return fields
+}
+
+func yamlIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// parseMap reads the "key: value" lines at the indentation of the first
+// line, returning the map and the number of lines consumed. Lines indented
+// further that belong to no value are skipped.
+func (d *yamlDocument) parseMap(start int) (map[string]any, int) {
+	fields := make(map[string]any)
+	if start >= len(d.lines) {
+		return fields, start
+	}
+
+	indent := d.lines[start].indent
+	i := start
+	for i < len(d.lines) && d.lines[i].indent >= indent {
+		key, value, ok := cutYamlKey(d.lines[i].text)
+		if !ok || d.lines[i].indent > indent {
+			i++
+			continue
+		}
+		fields[key], i = d.parseValue(value, i, indent)
+	}
+
+	return fields, i
+}
+
+// parseValue reads the value of the key or list item on line i, which may
+// go on over the following lines indented further than indent.
+func (d *yamlDocument) parseValue(value string, i int, indent int) (any, int) {
+	next := i + 1
+
+	if value == "" {
+		// an empty value is followed by an indented list or map, if any
+		switch {
+		case next < len(d.lines) && d.lines[next].indent >= indent && isYamlListItem(d.lines[next].text):
+			return d.parseList(next)
+		case next < len(d.lines) && d.lines[next].indent > indent:
+			if _, _, ok := cutYamlKey(d.lines[next].text); ok {
+				return d.parseMap(next)
+			}
+		default:
+			return nil, next
+		}
+	}
+
+	if header := stripComment(value); strings.HasPrefix(header, "|") || strings.HasPrefix(header, ">") {
+		return d.parseBlockScalar(header, i, indent)
+	}
+
+	// a plain or quoted scalar may be folded over more indented lines
+	var parts []string
+	if value != "" {
+		parts = append(parts, value)
+	}
+	for next < len(d.lines) && d.lines[next].indent > indent {
+		parts = append(parts, d.lines[next].text)
+		next++
+	}
+
+	return parseYamlScalar(strings.Join(parts, " ")), next
+}
+
+func (d *yamlDocument) parseList(start int) ([]any, int) {
+	var items []any
+
+	indent := d.lines[start].indent
+	i := start
+	for i < len(d.lines) && d.lines[i].indent == indent && isYamlListItem(d.lines[i].text) {
+		text := strings.TrimLeft(strings.TrimPrefix(d.lines[i].text, "-"), " ")
+
+		// "- key: value" starts a map whose keys line up with the first
+		if _, _, ok := cutYamlKey(text); ok {
+			d.lines[i].indent += len(d.lines[i].text) - len(text)
+			d.lines[i].text = text
+
+			var item map[string]any
+			item, i = d.parseMap(i)
+			items = append(items, item)
+			continue
+		}
+
+		// an empty item only has a value when the next line is nested
+		if text == "" && (i+1 == len(d.lines) || d.lines[i+1].indent <= indent) {
+			items = append(items, nil)
+			i++
+			continue
+		}
+
+		var item any
+		item, i = d.parseValue(text, i, indent)
+		items = append(items, item)
+	}
+
+	return items, i
+}
+
+// parseBlockScalar reads a literal "|" or folded ">" scalar from the lines
+// after line i that are indented further than indent. A "-" or "+" after
+// the indicator strips or keeps the trailing newlines.
+func (d *yamlDocument) parseBlockScalar(header string, i int, indent int) (string, int) {
+	next := i + 1
+	for next < len(d.lines) && d.lines[next].indent > indent {
+		next++
+	}
+
+	// the body runs up to the next line of the parent, blank lines included
+	first := d.lines[i].number + 1
+	last := len(d.source)
+	if next < len(d.lines) {
+		last = d.lines[next].number
+	}
+
+	var body []string
+	blockIndent := -1
+	for _, line := range d.source[first:last] {
+		if strings.TrimSpace(line) == "" {
+			body = append(body, "")
+			continue
+		}
+		if blockIndent < 0 {
+			blockIndent = yamlIndent(line)
+		}
+		body = append(body, strings.TrimPrefix(line, strings.Repeat(" ", min(blockIndent, yamlIndent(line)))))
+	}
+
+	trailing := 0
+	for len(body) > 0 && body[len(body)-1] == "" {
+		body = body[:len(body)-1]
+		trailing++
+	}
+	if len(body) == 0 {
+		return "", next
+	}
+
+	var content string
+	if header[0] == '|' {
+		content = strings.Join(body, "\n")
+	} else {
+		content = foldYaml(body)
+	}
+
+	switch {
+	case strings.Contains(header, "-"):
+		return content, next
+	case strings.Contains(header, "+"):
+		return content + strings.Repeat("\n", trailing+1), next
+	default:
+		return content + "\n", next
+	}
+}
+
+// foldYaml joins the lines of a folded scalar with spaces. Blank lines
+// become line breaks, and more indented lines keep theirs.
+func foldYaml(lines []string) string {
+	var folded strings.Builder
+	indented := func(line string) bool {
+		return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+	}
+
+	for i, line := range lines {
+		if i > 0 {
+			previous := lines[i-1]
+			switch {
+			case indented(line) || indented(previous):
+				folded.WriteString("\n")
+			case line == "" && previous != "":
+				// the break before a run of blank lines is dropped
+			case line == "" || previous == "":
+				folded.WriteString("\n")
+			default:
+				folded.WriteString(" ")
+			}
+		}
+		folded.WriteString(line)
+	}
+
+	return folded.String()
+}
+
+func isYamlListItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// cutYamlKey splits a "key: value" line, the colon being followed by a
+// space or the end of the line so that "http://example.com" is no key.
+func cutYamlKey(text string) (string, string, bool) {
+	if strings.HasPrefix(text, "- ") || text == "-" {
+		return "", "", false
+	}
+
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '"', '\'':
+			// a quoted key runs to its closing quote
+			if i == 0 {
+				if end := strings.IndexByte(text[1:], text[0]); end >= 0 {
+					i = end + 1
+				}
+			}
+		case ':':
+			if i+1 == len(text) || text[i+1] == ' ' || text[i+1] == '\t' {
+				return unquoteYaml(strings.TrimSpace(text[:i])), strings.TrimSpace(text[i+1:]), true
+			}
+		}
+	}
+
+	return "", "", false
+}
+
+var (
+	yamlInteger = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	yamlFloat   = regexp.MustCompile(`^[-+]?(?:\.[0-9]+|[0-9]+(?:\.[0-9]*)?)(?:[eE][-+]?[0-9]+)?$`)
+)
+
+// parseYamlScalar reads a scalar as the YAML 1.2 core schema would, except
+// that numbers are only read in decimal, so "0x1F" and ".inf" are strings,
+// as are "yes" and "no".
+func parseYamlScalar(value string) any {
+	value = stripComment(value)
+
+	if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+		var items []any
+		for _, item := range splitInlineList(value[1 : len(value)-1]) {
+			items = append(items, parseYamlScalar(item))
+		}
+		return items
+	}
+
+	switch value {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+
+	if yamlInteger.MatchString(value) {
+		if number, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return number
+		}
+	}
+	if yamlFloat.MatchString(value) {
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return number
+		}
+	}
+
+	return unquoteYaml(value)
+}
+
+func unquoteYaml(value string) string {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			return unquoted
+		}
+	}
+
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+	}
+
+	return value
+}
+
+func parseToml(lines []string) map[string]any {
+	fields := make(map[string]any)
+	table := fields
+
+	for _, line := range lines {
+		line = strings.TrimSpace(stripComment(line))
+		if line == "" {
+			continue
+		}
+
+		// a [table] header switches where the following keys go
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			table = fields
+			for _, name := range strings.Split(line[1:len(line)-1], ".") {
+				name = unquoteYaml(strings.TrimSpace(name))
+				next, ok := table[name].(map[string]any)
+				if !ok {
+					next = make(map[string]any)
+					table[name] = next
+				}
+				table = next
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		table[unquoteYaml(strings.TrimSpace(key))] = parseTomlValue(strings.TrimSpace(value))
+	}
+
+	return fields
+}
+
+var (
+	tomlInteger = regexp.MustCompile(`^[-+]?[0-9]+(?:_[0-9]+)*$`)
+	tomlFloat   = regexp.MustCompile(`^[-+]?[0-9]+(?:_[0-9]+)*(?:\.[0-9]+(?:_[0-9]+)*)?(?:[eE][-+]?[0-9]+(?:_[0-9]+)*)?$`)
+)
+
+// parseTomlValue reads strings, booleans, arrays and decimal numbers. Other
+// numbers, such as hexadecimal ones, inf and nan, are kept as strings.
+func parseTomlValue(value string) any {
+	if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+		var items []any
+		for _, item := range splitInlineList(value[1 : len(value)-1]) {
+			items = append(items, parseTomlValue(item))
+		}
+		return items
+	}
+
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	// underscores may only separate digits
+	if tomlInteger.MatchString(value) {
+		if number, err := strconv.ParseInt(strings.ReplaceAll(value, "_", ""), 10, 64); err == nil {
+			return number
+		}
+	}
+	if tomlFloat.MatchString(value) {
+		if number, err := strconv.ParseFloat(strings.ReplaceAll(value, "_", ""), 64); err == nil {
+			return number
+		}
+	}
+
+	return unquoteYaml(value)
+}
+
+// splitInlineList splits the items of a "[a, b]" list on the commas that
+// are not inside quotes.
+func splitInlineList(list string) []string {
+	var items []string
+	var item strings.Builder
+	var quote byte
+
+	for i := 0; i < len(list); i++ {
+		c := list[i]
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == ',':
+			items = append(items, strings.TrimSpace(item.String()))
+			item.Reset()
+			continue
+		}
+		item.WriteByte(c)
+	}
+
+	if last := strings.TrimSpace(item.String()); last != "" {
+		items = append(items, last)
+	}
+
+	return items
+}
+
+// stripComment drops a trailing "# comment" that is not inside quotes.
+func stripComment(value string) string {
+	var quote byte
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == '#' && (i == 0 || value[i-1] == ' ' || value[i-1] == '\t'):
+			return strings.TrimSpace(value[:i])
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,146 @@
+package parse_test
+
+import (
+	"allium/src/parse"
+	"reflect"
+	"testing"
+)
+
+func TestFrontMatterFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		format   string
+		fields   map[string]any
+	}{
+		{
+			name:     "YAML",
+			markdown: "---\ntitle: \"A: B\"\ndraft: true\ncount: 3\nratio: 0.5\ntags: [a, 'b c']\nauthors:\n  - Ann\n  - Bob\nauthor:\n  name: Ann # comment\nempty:\n...\n",
+			format:   "yaml",
+			fields: map[string]any{
+				"title":   "A: B",
+				"draft":   true,
+				"count":   int64(3),
+				"ratio":   0.5,
+				"tags":    []any{"a", "b c"},
+				"authors": []any{"Ann", "Bob"},
+				"author":  map[string]any{"name": "Ann"},
+				"empty":   nil,
+			},
+		},
+		{
+			name:     "YAML block scalars",
+			markdown: "---\nliteral: |\n  line one\n    indented\n\n  # not a comment\nfolded: >\n  one\n  two\n\n  three\nstrip: |-\n  text\nkeep: |+\n  text\n\nafter: value\n---\n",
+			format:   "yaml",
+			fields: map[string]any{
+				"literal": "line one\n  indented\n\n# not a comment\n",
+				"folded":  "one two\nthree\n",
+				"strip":   "text",
+				"keep":    "text\n\n",
+				"after":   "value",
+			},
+		},
+		{
+			name:     "YAML lists of maps",
+			markdown: "---\nauthors:\n  - name: Ann\n    email: ann@example.com\n  - name: Bob\n    roles: [editor]\nafter: value\n---\n",
+			format:   "yaml",
+			fields: map[string]any{
+				"authors": []any{
+					map[string]any{"name": "Ann", "email": "ann@example.com"},
+					map[string]any{"name": "Bob", "roles": []any{"editor"}},
+				},
+				"after": "value",
+			},
+		},
+		{
+			name:     "YAML continuation lines",
+			markdown: "---\ntitle: A long\n  title\nlist:\n- one\n  more\n-\n- three\nurl: http://example.com\nsummary:\n  on the next line\nafter: value\n---\n",
+			format:   "yaml",
+			fields: map[string]any{
+				"title":   "A long title",
+				"list":    []any{"one more", nil, "three"},
+				"url":     "http://example.com",
+				"summary": "on the next line",
+				"after":   "value",
+			},
+		},
+		{
+			name:     "YAML scalars",
+			markdown: "---\nint: -12\nfloat: 1.5e3\nhex: 0x1F\ninf: .inf\nnan: nan\ninfinity: Infinity\nyes: yes\nno: no\non: on\ntrue: True\nnull: NULL\nversion: 1.2.3\n---\n",
+			format:   "yaml",
+			fields: map[string]any{
+				"int":      int64(-12),
+				"float":    1500.0,
+				"hex":      "0x1F",
+				"inf":      ".inf",
+				"nan":      "nan",
+				"infinity": "Infinity",
+				"yes":      "yes",
+				"no":       "no",
+				"on":       "on",
+				"true":     true,
+				"null":     nil,
+				"version":  "1.2.3",
+			},
+		},
+		{
+			name:     "TOML numbers",
+			markdown: "+++\nint = +1_000\nfloat = 6.02e2_3\nhex = 0x1F\ninf = inf\nnan = nan\ndoubled = 1__0\ntrailing = 1_\n+++\n",
+			format:   "toml",
+			fields: map[string]any{
+				"int":      int64(1000),
+				"float":    6.02e23,
+				"hex":      "0x1F",
+				"inf":      "inf",
+				"nan":      "nan",
+				"doubled":  "1__0",
+				"trailing": "1_",
+			},
+		},
+		{
+			name:     "TOML",
+			markdown: "+++\ntitle = 'T'\ncount = 3\ntags = [\"a\", \"b\"]\n\n[author]\nname = \"Ann\"\n+++\n",
+			format:   "toml",
+			fields: map[string]any{
+				"title":  "T",
+				"count":  int64(3),
+				"tags":   []any{"a", "b"},
+				"author": map[string]any{"name": "Ann"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		frontMatter := parseMarkdown(test.markdown, parse.FrontMatterExtension).FrontMatter
+		if frontMatter == nil {
+			t.Errorf("%s: no front matter", test.name)
+			continue
+		}
+		if frontMatter.Format != test.format {
+			t.Errorf("%s: format %q, want %q", test.name, frontMatter.Format, test.format)
+		}
+		if !reflect.DeepEqual(frontMatter.Fields, test.fields) {
+			t.Errorf("%s: got fields\n%#v\nwant\n%#v", test.name, frontMatter.Fields, test.fields)
+		}
+	}
+}
+
+func TestFrontMatterBody(t *testing.T) {
+	document := parseMarkdown("---\ntitle: Hi\n---\n# x\n", parse.FrontMatterExtension)
+	if got := document.FrontMatter.Get("title"); got != "Hi" {
+		t.Errorf("title is %q, want Hi", got)
+	}
+	if len(document.Nodes) != 1 {
+		t.Errorf("front matter left %d nodes in the body, want 1", len(document.Nodes))
+	}
+
+	// without a closing fence, or without the extension, it is body text
+	for _, extensions := range []parse.Extension{parse.FrontMatterExtension, 0} {
+		if document := parseMarkdown("---\ntitle: Hi\n", extensions); document.FrontMatter != nil {
+			t.Errorf("unclosed front matter was read as %v", document.FrontMatter.Fields)
+		}
+	}
+	if document := parseMarkdown("---\ntitle: Hi\n---\n", 0); document.FrontMatter != nil {
+		t.Errorf("front matter was read without the extension")
+	}
+}
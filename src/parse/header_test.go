@@ -0,0 +1,31 @@
+package parse_test
+
+import (
+	"allium/src/parse"
+	"testing"
+)
+
+func TestExplicitHeaderIds(t *testing.T) {
+	tests := []struct {
+		markdown string
+		id       string
+		text     string
+	}{
+		{"# Title {#custom}\n", "custom", "Title"},
+		{"## Title {#a_b:c.d-e} ##\n", "a_b:c.d-e", "Title"},
+		{"Title {#setext}\n===\n", "setext", "Title"},
+		{"# Title {#not an id}\n", "", "Title {#not an id}"},
+		{"# Title {#x} more\n", "", "Title {#x} more"},
+	}
+
+	for _, test := range tests {
+		header := parseMarkdown(test.markdown, parse.HeaderIdsExtension).Nodes[0].(*parse.HeaderNode)
+		if text := parse.PlainText(header.Content); header.ID != test.id || text != test.text {
+			t.Errorf("%q: got ID %q and text %q, want %q and %q", test.markdown, header.ID, text, test.id, test.text)
+		}
+	}
+
+	if header := parseMarkdown("# Title {#custom}\n", 0).Nodes[0].(*parse.HeaderNode); header.ID != "" {
+		t.Errorf("got ID %q without the extension", header.ID)
+	}
+}
@@ -0,0 +1,1128 @@
+package parse
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// inlineParser parses the raw text of a single leaf block. Nodes are
+// collected into a linked list while emphasis delimiters and link brackets
+// are tracked on stacks, and are only nested once their closers are found.
+type inlineParser struct {
+	subject    string
+	pos        int
+	first      *inlineEntry
+	last       *inlineEntry
+	entries    map[Node]*inlineEntry
+	delimiters *delimiter
+	brackets   *bracket
+	references map[string]linkReference
+	footnotes  *footnoteIndex
+	extensions Extension
+
+	// offsets records the range of the subject each node was parsed from,
+	// start is where the inline being parsed began
+	offsets map[Node][2]int
+	start   int
+
+	// noDestinationBefore is where a failed link destination scan saw its
+	// last opening parenthesis; scans starting before it fail the same way
+	noDestinationBefore int
+
+	// unclosedHtml records the inline HTML openers, such as a comment, that
+	// have no closing marker anywhere in the rest of the subject
+	unclosedHtml map[string]bool
+}
+
+// inlineEntry links a parsed node to its neighbours, so that the nodes
+// between a pair of delimiters can be nested without moving the rest.
+type inlineEntry struct {
+	node     Node
+	previous *inlineEntry
+	next     *inlineEntry
+}
+
+type linkReference struct {
+	Link  string
+	Title string
+}
+
+type delimiter struct {
+	node          *TextNode
+	char          byte
+	count         int
+	originalCount int
+	canOpen       bool
+	canClose      bool
+	previous      *delimiter
+	next          *delimiter
+}
+
+type bracket struct {
+	node              *TextNode
+	index             int
+	isImage           bool
+	isActive          bool
+	bracketAfter      bool
+	previous          *bracket
+	previousDelimiter *delimiter
+}
+
+func (p *Parser) parseInlines(content string, source sourceMap) []Node {
+	var inline inlineParser
+	inline.subject = strings.Trim(content, " \t\n")
+	inline.references = p.references
+	inline.footnotes = p.footnotes
+	inline.extensions = p.Extensions
+	inline.offsets = make(map[Node][2]int)
+	inline.entries = make(map[Node]*inlineEntry)
+	inline.unclosedHtml = make(map[string]bool)
+
+	for inline.pos < len(inline.subject) {
+		inline.parseInline()
+	}
+
+	inline.processEmphasis(nil)
+	nodes := inline.mergeText(inline.nodesBetween(inline.first, nil))
+
+	source.trimmed += len(content) - len(strings.TrimLeft(content, " \t\n"))
+	inline.locate(nodes, source)
+
+	return nodes
+}
+
+// locate sets the span of each node from the subject range it was parsed
+// from.
+func (ip *inlineParser) locate(nodes []Node, source sourceMap) {
+	for _, node := range nodes {
+		if offsets, ok := ip.offsets[node]; ok {
+			node.base().span = source.span(offsets[0], offsets[1])
+		}
+		ip.locate(node.Children(), source)
+	}
+}
+
+func (ip *inlineParser) parseInline() {
+	ip.start = ip.pos
+
+	switch ip.subject[ip.pos] {
+	case '\n':
+		ip.parseNewLine()
+	case '\\':
+		ip.parseBackslash()
+	case '`':
+		ip.parseInlineCode()
+	case '*', '_':
+		ip.parseDelimiterRun()
+	case '~':
+		if ip.extensions&StrikethroughExtension != 0 {
+			ip.parseDelimiterRun()
+		} else {
+			ip.parseText()
+		}
+	case '[':
+		if ip.extensions&FootnotesExtension != 0 && peek(ip.subject, ip.pos+1) == '^' && ip.parseFootnoteReference() {
+			break
+		}
+
+		ip.pos++
+		ip.appendBracket(ip.pos-1, false)
+	case '!':
+		if peek(ip.subject, ip.pos+1) == '[' {
+			ip.pos += 2
+			ip.appendBracket(ip.pos-1, true)
+		} else {
+			ip.pos++
+			ip.appendText("!")
+		}
+	case ']':
+		ip.parseCloseBracket()
+	case '<':
+		ip.parseAngleBracket()
+	case '&':
+		ip.parseEntity()
+	default:
+		ip.parseText()
+	}
+}
+
+func (ip *inlineParser) append(node Node) {
+	entry := &inlineEntry{node: node, previous: ip.last}
+	if ip.last == nil {
+		ip.first = entry
+	} else {
+		ip.last.next = entry
+	}
+	ip.last = entry
+	ip.entries[node] = entry
+	ip.offsets[node] = [2]int{ip.start, ip.pos}
+}
+
+// nodesBetween takes the nodes from first up to but not including end out
+// of the list, to be nested in another node.
+func (ip *inlineParser) nodesBetween(first *inlineEntry, end *inlineEntry) []Node {
+	var nodes []Node
+	for entry := first; entry != end; entry = entry.next {
+		nodes = append(nodes, entry.node)
+		delete(ip.entries, entry.node)
+	}
+
+	return nodes
+}
+
+func (ip *inlineParser) appendText(content string) *TextNode {
+	node := &TextNode{Content: content}
+	ip.append(node)
+
+	return node
+}
+
+func (ip *inlineParser) parseText() {
+	start := ip.pos
+	for ip.pos < len(ip.subject) && !strings.ContainsRune("\n\\`*_~[]!<&", rune(ip.subject[ip.pos])) {
+		ip.pos++
+	}
+
+	if ip.pos == start {
+		ip.pos++
+	}
+	ip.appendText(ip.subject[start:ip.pos])
+}
+
+// parseNewLine turns a line ending into a soft break, or a hard break when
+// the line ended with two or more spaces.
+func (ip *inlineParser) parseNewLine() {
+	ip.pos++
+
+	isHardBreak := false
+	if ip.last != nil {
+		if text, ok := ip.last.node.(*TextNode); ok && strings.HasSuffix(text.Content, " ") {
+			isHardBreak = strings.HasSuffix(text.Content, "  ")
+			text.Content = strings.TrimRight(text.Content, " ")
+		}
+	}
+
+	if isHardBreak {
+		ip.append(&LineBreakNode{})
+	} else {
+		ip.append(&NewLineNode{})
+	}
+
+	for peek(ip.subject, ip.pos) == ' ' {
+		ip.pos++
+	}
+}
+
+func (ip *inlineParser) parseBackslash() {
+	ip.pos++
+
+	next := peek(ip.subject, ip.pos)
+	switch {
+	case next == '\n':
+		ip.pos++
+		ip.append(&LineBreakNode{})
+	case isAsciiPunctuation(next):
+		ip.pos++
+		ip.appendText(string(next))
+	default:
+		ip.appendText("\\")
+	}
+}
+
+func (ip *inlineParser) parseInlineCode() {
+	start := ip.pos
+	for peek(ip.subject, ip.pos) == '`' {
+		ip.pos++
+	}
+	openLength := ip.pos - start
+	afterOpen := ip.pos
+
+	for ip.pos < len(ip.subject) {
+		if ip.subject[ip.pos] != '`' {
+			ip.pos++
+			continue
+		}
+
+		runStart := ip.pos
+		for peek(ip.subject, ip.pos) == '`' {
+			ip.pos++
+		}
+
+		if ip.pos-runStart == openLength {
+			var node InlineCodeNode
+			node.Content = normaliseCodeSpan(ip.subject[afterOpen:runStart])
+			ip.append(&node)
+			return
+		}
+	}
+
+	// no closing run of the same length, so the opening backticks are literal
+	ip.pos = afterOpen
+	ip.appendText(ip.subject[start:afterOpen])
+}
+
+func normaliseCodeSpan(content string) string {
+	content = strings.ReplaceAll(content, "\n", " ")
+
+	if len(content) >= 2 && content[0] == ' ' && content[len(content)-1] == ' ' && strings.Trim(content, " ") != "" {
+		content = content[1 : len(content)-1]
+	}
+
+	return content
+}
+
+func (ip *inlineParser) parseDelimiterRun() {
+	char := ip.subject[ip.pos]
+	count, canOpen, canClose := ip.scanDelimiters(char)
+
+	start := ip.pos
+	ip.pos += count
+	node := ip.appendText(ip.subject[start:ip.pos])
+
+	if !canOpen && !canClose {
+		return
+	}
+
+	// strikethrough only uses runs of one or two tildes
+	if char == '~' && count > 2 {
+		return
+	}
+
+	var d delimiter
+	d.node = node
+	d.char = char
+	d.count = count
+	d.originalCount = count
+	d.canOpen = canOpen
+	d.canClose = canClose
+	d.previous = ip.delimiters
+	if d.previous != nil {
+		d.previous.next = &d
+	}
+	ip.delimiters = &d
+}
+
+// scanDelimiters measures the delimiter run at the current position and
+// decides from its flanking whether it can open or close emphasis.
+func (ip *inlineParser) scanDelimiters(char byte) (int, bool, bool) {
+	count := 0
+	for peek(ip.subject, ip.pos+count) == char {
+		count++
+	}
+
+	before := '\n'
+	if ip.pos > 0 {
+		before, _ = utf8.DecodeLastRuneInString(ip.subject[:ip.pos])
+	}
+
+	after := '\n'
+	if ip.pos+count < len(ip.subject) {
+		after, _ = utf8.DecodeRuneInString(ip.subject[ip.pos+count:])
+	}
+
+	afterIsWhiteSpace := unicode.IsSpace(after)
+	afterIsPunctuation := isPunctuation(after)
+	beforeIsWhiteSpace := unicode.IsSpace(before)
+	beforeIsPunctuation := isPunctuation(before)
+
+	leftFlanking := !afterIsWhiteSpace && (!afterIsPunctuation || beforeIsWhiteSpace || beforeIsPunctuation)
+	rightFlanking := !beforeIsWhiteSpace && (!beforeIsPunctuation || afterIsWhiteSpace || afterIsPunctuation)
+
+	if char == '_' {
+		canOpen := leftFlanking && (!rightFlanking || beforeIsPunctuation)
+		canClose := rightFlanking && (!leftFlanking || afterIsPunctuation)
+		return count, canOpen, canClose
+	}
+
+	return count, leftFlanking, rightFlanking
+}
+
+func (ip *inlineParser) removeDelimiter(d *delimiter) {
+	if d.previous != nil {
+		d.previous.next = d.next
+	}
+
+	if d.next == nil {
+		ip.delimiters = d.previous
+	} else {
+		d.next.previous = d.previous
+	}
+}
+
+func (ip *inlineParser) removeNode(node Node) {
+	entry, ok := ip.entries[node]
+	if !ok {
+		return
+	}
+	delete(ip.entries, node)
+
+	if entry.previous == nil {
+		ip.first = entry.next
+	} else {
+		entry.previous.next = entry.next
+	}
+
+	if entry.next == nil {
+		ip.last = entry.previous
+	} else {
+		entry.next.previous = entry.previous
+	}
+}
+
+// processEmphasis matches closers against openers above stackBottom and
+// nests the nodes between each matched pair into emphasis nodes.
+func (ip *inlineParser) processEmphasis(stackBottom *delimiter) {
+	openersBottom := make(map[[3]int]*delimiter)
+
+	// start from the first delimiter above stackBottom, if there is one
+	var closer *delimiter
+	for d := ip.delimiters; d != nil && d != stackBottom; d = d.previous {
+		closer = d
+	}
+
+	for closer != nil {
+		if !closer.canClose {
+			closer = closer.next
+			continue
+		}
+
+		canOpen := 0
+		if closer.canOpen {
+			canOpen = 1
+		}
+		bottomKey := [3]int{int(closer.char), canOpen, closer.originalCount % 3}
+		bottom, ok := openersBottom[bottomKey]
+		if !ok {
+			bottom = stackBottom
+		}
+
+		opener := closer.previous
+		openerFound := false
+		for opener != nil && opener != stackBottom && opener != bottom {
+			oddMatch := (closer.canOpen || opener.canClose) && closer.originalCount%3 != 0 &&
+				(opener.originalCount+closer.originalCount)%3 == 0
+			if closer.char == '~' {
+				// tilde runs only close runs of the same length
+				oddMatch = opener.count != closer.count
+			}
+
+			if opener.char == closer.char && opener.canOpen && !oddMatch {
+				openerFound = true
+				break
+			}
+			opener = opener.previous
+		}
+
+		oldCloser := closer
+		if openerFound {
+			closer = ip.matchEmphasis(opener, closer)
+		} else {
+			closer = closer.next
+
+			openersBottom[bottomKey] = oldCloser.previous
+			if !oldCloser.canOpen {
+				ip.removeDelimiter(oldCloser)
+			}
+		}
+	}
+
+	for ip.delimiters != nil && ip.delimiters != stackBottom {
+		ip.removeDelimiter(ip.delimiters)
+	}
+}
+
+// matchEmphasis wraps the nodes between opener and closer and returns the
+// delimiter to continue processing from.
+func (ip *inlineParser) matchEmphasis(opener *delimiter, closer *delimiter) *delimiter {
+	used := 1
+	if closer.char == '~' {
+		used = closer.count
+	} else if closer.count >= 2 && opener.count >= 2 {
+		used = 2
+	}
+
+	opener.count -= used
+	closer.count -= used
+	opener.node.Content = opener.node.Content[:len(opener.node.Content)-used]
+	closer.node.Content = closer.node.Content[:len(closer.node.Content)-used]
+
+	// the opener gives up the end of its run and the closer the start
+	openerOffsets := ip.offsets[opener.node]
+	closerOffsets := ip.offsets[closer.node]
+	ip.offsets[opener.node] = [2]int{openerOffsets[0], openerOffsets[1] - used}
+	ip.offsets[closer.node] = [2]int{closerOffsets[0] + used, closerOffsets[1]}
+
+	start := ip.entries[opener.node]
+	end := ip.entries[closer.node]
+	children := ip.nodesBetween(start.next, end)
+
+	var emphasis Node
+	if closer.char == '~' {
+		emphasis = &StrikethroughNode{Nodes: children}
+	} else if used == 1 {
+		emphasis = &ItalicNode{Nodes: children}
+	} else {
+		emphasis = &BoldNode{Nodes: children}
+	}
+
+	ip.offsets[emphasis] = [2]int{openerOffsets[1] - used, closerOffsets[0] + used}
+
+	entry := &inlineEntry{node: emphasis, previous: start, next: end}
+	start.next = entry
+	end.previous = entry
+	ip.entries[emphasis] = entry
+
+	// delimiters between the pair can no longer match anything
+	opener.next = closer
+	closer.previous = opener
+
+	if opener.count == 0 {
+		ip.removeNode(opener.node)
+		ip.removeDelimiter(opener)
+	}
+
+	if closer.count == 0 {
+		ip.removeNode(closer.node)
+		next := closer.next
+		ip.removeDelimiter(closer)
+		return next
+	}
+
+	return closer
+}
+
+func (ip *inlineParser) appendBracket(index int, isImage bool) {
+	content := "["
+	if isImage {
+		content = "!["
+	}
+	node := ip.appendText(content)
+
+	if ip.brackets != nil {
+		ip.brackets.bracketAfter = true
+	}
+
+	var b bracket
+	b.node = node
+	b.index = index
+	b.isImage = isImage
+	b.isActive = true
+	b.previous = ip.brackets
+	b.previousDelimiter = ip.delimiters
+	ip.brackets = &b
+}
+
+func (ip *inlineParser) parseCloseBracket() {
+	ip.pos++
+	start := ip.pos
+
+	opener := ip.brackets
+	if opener == nil {
+		ip.appendText("]")
+		return
+	}
+
+	if !opener.isActive {
+		ip.appendText("]")
+		ip.brackets = opener.previous
+		return
+	}
+
+	link, title, matched := ip.parseLinkTarget(opener, start)
+	if !matched {
+		ip.brackets = opener.previous
+		ip.pos = start
+		ip.appendText("]")
+		return
+	}
+
+	// emphasis inside the link text is resolved before it is nested
+	ip.processEmphasis(opener.previousDelimiter)
+
+	// the link replaces its opening bracket and everything after it
+	entry := ip.entries[opener.node]
+	children := ip.mergeText(ip.nodesBetween(entry.next, nil))
+	ip.last = entry.previous
+	if ip.last == nil {
+		ip.first = nil
+	} else {
+		ip.last.next = nil
+	}
+
+	// the link starts at its opening bracket, including the "!" of an image
+	ip.start = ip.offsets[opener.node][0]
+
+	if opener.isImage {
+		var node ImageNode
+		node.LinkText = PlainText(children)
+		node.Link = link
+		node.Title = title
+		ip.append(&node)
+	} else {
+		var node LinkNode
+		node.Nodes = children
+		node.Link = link
+		node.Title = title
+		ip.append(&node)
+	}
+
+	ip.brackets = opener.previous
+
+	// links cannot contain other links, so earlier openers are deactivated
+	if !opener.isImage {
+		for b := ip.brackets; b != nil; b = b.previous {
+			if !b.isImage {
+				b.isActive = false
+			}
+		}
+	}
+}
+
+// parseLinkTarget looks for an inline destination or a reference label
+// after a closing bracket.
+func (ip *inlineParser) parseLinkTarget(opener *bracket, start int) (string, string, bool) {
+	if peek(ip.subject, ip.pos) == '(' {
+		ip.pos++
+		ip.skipSpaceAndNewLine()
+
+		if link, ok := ip.parseLinkDestination(); ok {
+			title := ""
+			beforeTitle := ip.pos
+			ip.skipSpaceAndNewLine()
+			if ip.pos > beforeTitle {
+				if parsed, ok := ip.parseLinkTitle(); ok {
+					title = parsed
+				}
+			}
+			ip.skipSpaceAndNewLine()
+
+			if peek(ip.subject, ip.pos) == ')' {
+				ip.pos++
+				return link, title, true
+			}
+		}
+
+		ip.pos = start
+	}
+
+	label := ""
+	beforeLabel := ip.pos
+	length := ip.parseLinkLabel()
+	if length > 2 {
+		label = ip.subject[beforeLabel : beforeLabel+length]
+	} else if !opener.bracketAfter {
+		// a missing or empty second label uses the link text as the label
+		label = ip.subject[opener.index:start]
+	}
+
+	if length == 0 {
+		ip.pos = start
+	}
+
+	if label != "" {
+		if reference, ok := ip.references[normaliseReference(label)]; ok {
+			return reference.Link, reference.Title, true
+		}
+	}
+
+	return "", "", false
+}
+
+var (
+	emailAutolink = regexp.MustCompile(`^<([a-zA-Z0-9.!#$%&'*+/=?^_` + "`" + `{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*)>`)
+	uriAutolink   = regexp.MustCompile(`^<[A-Za-z][A-Za-z0-9.+-]{1,31}:[^<>\x00-\x20]*>`)
+	htmlInline    = regexp.MustCompile(`^(?i)` + htmlTag)
+)
+
+// htmlClosers pairs the inline HTML openers that may run on to the end of
+// the subject with the marker that closes them, longest opener first.
+var htmlClosers = [][2]string{
+	{"<![CDATA[", "]]>"},
+	{"<!--", "-->"},
+	{"<!", ">"},
+	{"<?", "?>"},
+}
+
+func (ip *inlineParser) parseAngleBracket() {
+	rest := ip.subject[ip.pos:]
+
+	if match := emailAutolink.FindStringSubmatch(rest); match != nil {
+		ip.pos += len(match[0])
+
+		text := &TextNode{Content: match[1]}
+		ip.offsets[text] = [2]int{ip.start + 1, ip.pos - 1}
+
+		var node LinkNode
+		node.Link = normaliseUri("mailto:" + match[1])
+		node.Nodes = []Node{text}
+		ip.append(&node)
+		return
+	}
+
+	if match := uriAutolink.FindString(rest); match != "" {
+		ip.pos += len(match)
+		uri := match[1 : len(match)-1]
+
+		text := &TextNode{Content: uri}
+		ip.offsets[text] = [2]int{ip.start + 1, ip.pos - 1}
+
+		var node LinkNode
+		node.Link = normaliseUri(uri)
+		node.Nodes = []Node{text}
+		ip.append(&node)
+		return
+	}
+
+	opener, closer := htmlCloser(rest)
+	if !ip.unclosedHtml[opener] {
+		if match := htmlInline.FindString(rest); match != "" {
+			ip.pos += len(match)
+			ip.append(&HtmlInlineNode{Content: match})
+			return
+		}
+
+		// once the closer is missing it stays missing, so later openers of
+		// the same kind are not scanned to the end of the subject again
+		if opener != "" && !strings.Contains(rest[1:], closer) {
+			ip.unclosedHtml[opener] = true
+		}
+	}
+
+	ip.pos++
+	ip.appendText("<")
+}
+
+func htmlCloser(s string) (string, string) {
+	for _, pair := range htmlClosers {
+		if len(s) >= len(pair[0]) && strings.EqualFold(s[:len(pair[0])], pair[0]) {
+			return pair[0], pair[1]
+		}
+	}
+
+	return "", ""
+}
+
+var entity = regexp.MustCompile(`^&(?:#[xX][0-9a-fA-F]{1,6}|#[0-9]{1,7}|[a-zA-Z][a-zA-Z0-9]{1,31});`)
+
+func (ip *inlineParser) parseEntity() {
+	if match := entity.FindString(ip.subject[ip.pos:]); match != "" {
+		ip.pos += len(match)
+		ip.appendText(decodeEntity(match))
+		return
+	}
+
+	ip.pos++
+	ip.appendText("&")
+}
+
+func decodeEntity(match string) string {
+	decoded := html.UnescapeString(match)
+
+	// named entities are also decoded by prefix, so "&notit;" comes back as
+	// "¬it;" even though it is not an entity
+	if match[1] != '#' && len(decoded) > 1 && strings.HasSuffix(decoded, ";") {
+		return match
+	}
+
+	return decoded
+}
+
+func (ip *inlineParser) skipSpaceAndNewLine() {
+	ip.pos = skipSpaceAndNewLine(ip.subject, ip.pos)
+}
+
+// skipSpaceAndNewLine skips spaces and tabs with at most one line ending
+// among them.
+func skipSpaceAndNewLine(s string, pos int) int {
+	seenNewLine := false
+	for pos < len(s) {
+		switch s[pos] {
+		case ' ', '\t':
+		case '\n':
+			if seenNewLine {
+				return pos
+			}
+			seenNewLine = true
+		default:
+			return pos
+		}
+		pos++
+	}
+
+	return pos
+}
+
+func (ip *inlineParser) parseLinkDestination() (string, bool) {
+	if ip.pos < ip.noDestinationBefore {
+		return "", false
+	}
+
+	destination, end, ok := parseLinkDestination(ip.subject, ip.pos)
+	if ok {
+		ip.pos = end
+	} else if open := unclosedParenthesis(ip.subject, ip.pos); open >= 0 {
+		ip.noDestinationBefore = open + 1
+	}
+
+	return destination, ok
+}
+
+// maxLinkParentheses limits the nesting of parentheses in a bare link
+// destination, as cmark does, so each scan stays short.
+const maxLinkParentheses = 32
+
+func parseLinkDestination(s string, pos int) (string, int, bool) {
+	if peek(s, pos) == '<' {
+		for i := pos + 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '\n', '<':
+				return "", pos, false
+			case '>':
+				return normaliseUri(unescapeString(s[pos+1 : i])), i + 1, true
+			}
+		}
+		return "", pos, false
+	}
+
+	start := pos
+	depth := 0
+	for pos < len(s) {
+		c := s[pos]
+		if c == '\\' && isAsciiPunctuation(peek(s, pos+1)) {
+			pos += 2
+		} else if c == '(' {
+			depth++
+			if depth > maxLinkParentheses {
+				return "", start, false
+			}
+			pos++
+		} else if c == ')' {
+			if depth < 1 {
+				break
+			}
+			depth--
+			pos++
+		} else if c <= ' ' || c == 0x7f {
+			break
+		} else {
+			pos++
+		}
+	}
+
+	if (pos == start && peek(s, pos) != ')') || depth != 0 {
+		return "", start, false
+	}
+
+	return normaliseUri(unescapeString(s[start:pos])), pos, true
+}
+
+// unclosedParenthesis returns the last opening parenthesis of a bare link
+// destination that failed because nothing closed it, or -1 if it failed
+// some other way. Every bracket opened before that parenthesis and within
+// the same run of characters would scan to the same end and fail too.
+func unclosedParenthesis(s string, pos int) int {
+	if peek(s, pos) == '<' {
+		return -1
+	}
+
+	open := -1
+	for pos < len(s) {
+		c := s[pos]
+		if c == '\\' && isAsciiPunctuation(peek(s, pos+1)) {
+			pos += 2
+		} else if c == ')' {
+			return -1
+		} else if c <= ' ' || c == 0x7f {
+			break
+		} else {
+			if c == '(' {
+				open = pos
+			}
+			pos++
+		}
+	}
+
+	return open
+}
+
+func (ip *inlineParser) parseLinkTitle() (string, bool) {
+	title, end, ok := parseLinkTitle(ip.subject, ip.pos)
+	if ok {
+		ip.pos = end
+	}
+
+	return title, ok
+}
+
+func parseLinkTitle(s string, pos int) (string, int, bool) {
+	closer := byte(0)
+	switch peek(s, pos) {
+	case '"':
+		closer = '"'
+	case '\'':
+		closer = '\''
+	case '(':
+		closer = ')'
+	default:
+		return "", pos, false
+	}
+
+	for i := pos + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case closer:
+			return unescapeString(s[pos+1 : i]), i + 1, true
+		case '(':
+			if closer == ')' {
+				return "", pos, false
+			}
+		}
+	}
+
+	return "", pos, false
+}
+
+func (ip *inlineParser) parseLinkLabel() int {
+	return parseLinkLabel(ip.subject, ip.pos, &ip.pos)
+}
+
+// parseLinkLabel returns the length of the bracketed label starting at pos,
+// or 0 if there is none, advancing end past it when found.
+func parseLinkLabel(s string, pos int, end *int) int {
+	if peek(s, pos) != '[' {
+		return 0
+	}
+
+	for i := pos + 1; i < len(s) && i-pos <= 1000; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			return 0
+		case ']':
+			*end = i + 1
+			return i + 1 - pos
+		}
+	}
+
+	return 0
+}
+
+// parseReference parses a link reference definition at the start of s,
+// records it and returns the number of bytes it spans.
+func parseReference(s string, references map[string]linkReference) int {
+	pos := 0
+	labelLength := parseLinkLabel(s, pos, &pos)
+	if labelLength == 0 || peek(s, pos) != ':' {
+		return 0
+	}
+	label := s[:labelLength]
+	pos++
+
+	pos = skipSpaceAndNewLine(s, pos)
+	link, pos, ok := parseLinkDestination(s, pos)
+	if !ok || (link == "" && s[pos-1] != '>') {
+		return 0
+	}
+
+	beforeTitle := pos
+	pos = skipSpaceAndNewLine(s, pos)
+	title := ""
+	if pos > beforeTitle {
+		if parsed, end, ok := parseLinkTitle(s, pos); ok {
+			title = parsed
+			pos = end
+		} else {
+			pos = beforeTitle
+		}
+	} else {
+		pos = beforeTitle
+	}
+
+	// the definition has to end the line, if a title is followed by more
+	// text the definition may still be valid without it
+	end, atLineEnd := skipToLineEnd(s, pos)
+	if !atLineEnd && title != "" {
+		title = ""
+		end, atLineEnd = skipToLineEnd(s, beforeTitle)
+	}
+	if !atLineEnd {
+		return 0
+	}
+
+	normalised := normaliseReference(label)
+	if normalised == "" {
+		return 0
+	}
+
+	if _, ok := references[normalised]; !ok {
+		references[normalised] = linkReference{Link: link, Title: title}
+	}
+
+	return end
+}
+
+func skipToLineEnd(s string, pos int) (int, bool) {
+	for pos < len(s) && isSpaceOrTab(s[pos]) {
+		pos++
+	}
+
+	if pos == len(s) {
+		return pos, true
+	}
+	if s[pos] == '\n' {
+		return pos + 1, true
+	}
+
+	return pos, false
+}
+
+func normaliseReference(label string) string {
+	label = strings.Join(strings.Fields(label[1:len(label)-1]), " ")
+	return strings.ToUpper(strings.ToLower(label))
+}
+
+var escapedCharOrEntity = regexp.MustCompile(`\\[!"#$%&'()*+,./:;<=>?@[\\\]^_` + "`" + `{|}~-]|&(?:#[xX][0-9a-fA-F]{1,6}|#[0-9]{1,7}|[a-zA-Z][a-zA-Z0-9]{1,31});`)
+
+func unescapeString(s string) string {
+	if !strings.ContainsAny(s, "\\&") {
+		return s
+	}
+
+	return escapedCharOrEntity.ReplaceAllStringFunc(s, func(match string) string {
+		if match[0] == '\\' {
+			return match[1:]
+		}
+		return decodeEntity(match)
+	})
+}
+
+const uriSafe = ";/?:@&=+$,-_.!~*'()#"
+
+// normaliseUri percent-encodes the characters of a destination that are not
+// allowed in a URI, leaving existing escapes untouched.
+func normaliseUri(uri string) string {
+	var result strings.Builder
+
+	for i := 0; i < len(uri); i++ {
+		c := uri[i]
+		switch {
+		case c == '%' && i+2 < len(uri) && isHex(uri[i+1]) && isHex(uri[i+2]):
+			result.WriteString(uri[i : i+3])
+			i += 2
+		case c < utf8.RuneSelf && (isAlphaNumeric(c) || strings.IndexByte(uriSafe, c) >= 0):
+			result.WriteByte(c)
+		default:
+			result.WriteString("%")
+			result.WriteByte("0123456789ABCDEF"[c>>4])
+			result.WriteByte("0123456789ABCDEF"[c&15])
+		}
+	}
+
+	return result.String()
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isAlphaNumeric(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isAsciiPunctuation(c byte) bool {
+	return c != 0 && c < utf8.RuneSelf && strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isPunctuation(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+// mergeText joins adjacent text nodes, which the delimiter and bracket
+// handling leaves split up. Each run of text is joined in one go, into the
+// first node of the run that has any content.
+func (ip *inlineParser) mergeText(nodes []Node) []Node {
+	var merged []Node
+
+	for i := 0; i < len(nodes); {
+		if _, ok := nodes[i].(*TextNode); !ok {
+			merged = append(merged, ip.mergeChildren(nodes[i]))
+			i++
+			continue
+		}
+
+		var first, last *TextNode
+		var content strings.Builder
+		for ; i < len(nodes); i++ {
+			text, ok := nodes[i].(*TextNode)
+			if !ok {
+				break
+			}
+			if text.Content == "" {
+				continue
+			}
+			if first == nil {
+				first = text
+			}
+			last = text
+			content.WriteString(text.Content)
+		}
+
+		if first != nil {
+			first.Content = content.String()
+			ip.offsets[first] = [2]int{ip.offsets[first][0], ip.offsets[last][1]}
+			merged = append(merged, first)
+		}
+	}
+
+	return merged
+}
+
+func (ip *inlineParser) mergeChildren(node Node) Node {
+	switch node := node.(type) {
+	case *ItalicNode:
+		node.Nodes = ip.mergeText(node.Nodes)
+	case *BoldNode:
+		node.Nodes = ip.mergeText(node.Nodes)
+	case *StrikethroughNode:
+		node.Nodes = ip.mergeText(node.Nodes)
+	}
+
+	return node
+}
+
+// PlainText flattens inline nodes into the text used for image alt text.
+func PlainText(nodes []Node) string {
+	var text strings.Builder
+
+	for _, node := range nodes {
+		switch node := node.(type) {
+		case *TextNode:
+			text.WriteString(node.Content)
+		case *InlineCodeNode:
+			text.WriteString(node.Content)
+		case *ItalicNode:
+			text.WriteString(PlainText(node.Nodes))
+		case *BoldNode:
+			text.WriteString(PlainText(node.Nodes))
+		case *StrikethroughNode:
+			text.WriteString(PlainText(node.Nodes))
+		case *LinkNode:
+			text.WriteString(PlainText(node.Nodes))
+		case *ImageNode:
+			text.WriteString(node.LinkText)
+		case *HtmlInlineNode:
+			text.WriteString(node.Content)
+		case *NewLineNode, *LineBreakNode:
+			text.WriteString(" ")
+		}
+	}
+
+	return text.String()
+}
@@ -0,0 +1,97 @@
+package parse_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnclosedInlines(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		html     string
+	}{
+		{
+			name:     "link inside an unclosed destination",
+			markdown: "[a](b(c[d](e)",
+			html:     "<p>[a](b(c<a href=\"e\">d</a></p>\n",
+		},
+		{
+			name:     "unclosed destinations",
+			markdown: "[a]([b](",
+			html:     "<p>[a]([b](</p>\n",
+		},
+		{
+			name:     "nested parentheses",
+			markdown: "[a](b(c(d)))",
+			html:     "<p><a href=\"b(c(d))\">a</a></p>\n",
+		},
+		{
+			name:     "too deeply nested parentheses",
+			markdown: "[a](" + strings.Repeat("(", 33) + strings.Repeat(")", 34),
+			html:     "<p>[a](" + strings.Repeat("(", 33) + strings.Repeat(")", 34) + "</p>\n",
+		},
+		{
+			name:     "comments after an unclosed comment",
+			markdown: "a <!-- b <!-- c -->",
+			html:     "<p>a <!-- b <!-- c --></p>\n",
+		},
+		{
+			name:     "unclosed comment",
+			markdown: "a <!-- b <!-- c",
+			html:     "<p>a &lt;!-- b &lt;!-- c</p>\n",
+		},
+		{
+			name:     "unclosed processing instruction",
+			markdown: "a <? b <? c",
+			html:     "<p>a &lt;? b &lt;? c</p>\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if html := render(test.markdown, 0); html != test.html {
+				t.Errorf("got %q, want %q", html, test.html)
+			}
+		})
+	}
+}
+
+// TestInlineScaling checks that parsing inputs which leave many openers
+// unmatched takes time linear in their length.
+func TestInlineScaling(t *testing.T) {
+	if testing.Short() {
+		t.Skip("timing test")
+	}
+
+	patterns := []string{"[a](", "[a](()", "*a **a ", "a <", "a <!--", "a <?", "a <!x", "a <![CDATA[", "`a ``", "[a"}
+
+	for _, pattern := range patterns {
+		t.Run(pattern, func(t *testing.T) {
+			small := parseTime(strings.Repeat(pattern, 1000))
+			large := parseTime(strings.Repeat(pattern, 8000))
+
+			// eight times the input would take about sixty-four times as
+			// long if parsing were quadratic
+			if large > 24*small {
+				t.Errorf("8x the input took %.1fx as long", float64(large)/float64(small))
+			}
+		})
+	}
+}
+
+// parseTime returns the fastest of a few parses of source, to keep the
+// comparison steady.
+func parseTime(source string) time.Duration {
+	var fastest time.Duration
+	for i := 0; i < 3; i++ {
+		start := time.Now()
+		parseMarkdown(source, 0)
+		if elapsed := time.Since(start); i == 0 || elapsed < fastest {
+			fastest = elapsed
+		}
+	}
+
+	return fastest
+}
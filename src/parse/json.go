@@ -0,0 +1,357 @@
+package parse
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// JSONVersion is the version of the schema written by EncodeJSON. It only
+// changes when existing fields change meaning or are removed.
+const JSONVersion = 1
+
+// The JSON form of a document is
+//
+//	{"version": 1, "document": <node>}
+//
+// where every node is an object with its kind name, its span, the
+// attributes of its kind and its children:
+//
+//	{
+//	  "kind": "Link",
+//	  "span": {"start": {"line": 1, "column": 1}, "end": {"line": 1, "column": 20}},
+//	  "attributes": {"link": "https://example.com", "title": ""},
+//	  "children": [...]
+//	}
+//
+// The document node also carries "footnotes", its footnote definitions,
+// and "frontMatter" when it has any.
+type jsonDocument struct {
+	Version  int       `json:"version"`
+	Document *jsonNode `json:"document"`
+}
+
+type jsonNode struct {
+	Kind        string           `json:"kind"`
+	Span        Span             `json:"span"`
+	Attributes  map[string]any   `json:"attributes,omitempty"`
+	Children    []*jsonNode      `json:"children,omitempty"`
+	Footnotes   []*jsonNode      `json:"footnotes,omitempty"`
+	FrontMatter *jsonFrontMatter `json:"frontMatter,omitempty"`
+}
+
+type jsonFrontMatter struct {
+	Format string         `json:"format"`
+	Raw    string         `json:"raw"`
+	Fields map[string]any `json:"fields"`
+}
+
+// EncodeJSON serialises the document and everything in it, indented for
+// reading.
+func EncodeJSON(document *DocumentNode) ([]byte, error) {
+	var out jsonDocument
+	out.Version = JSONVersion
+	out.Document = encodeNode(document)
+
+	return json.MarshalIndent(out, "", "  ")
+}
+
+func encodeNode(node Node) *jsonNode {
+	var out jsonNode
+	out.Kind = node.Kind().String()
+	out.Span = node.Span()
+	out.Attributes = nodeAttributes(node)
+
+	for _, child := range node.Children() {
+		out.Children = append(out.Children, encodeNode(child))
+	}
+
+	if document, ok := node.(*DocumentNode); ok {
+		for _, footnote := range document.Footnotes {
+			out.Footnotes = append(out.Footnotes, encodeNode(footnote))
+		}
+		if document.FrontMatter != nil {
+			out.FrontMatter = &jsonFrontMatter{
+				Format: document.FrontMatter.Format,
+				Raw:    document.FrontMatter.Raw,
+				Fields: document.FrontMatter.Fields,
+			}
+		}
+	}
+
+	return &out
+}
+
+// nodeAttributes lists every attribute of a node, including those left at
+// their zero value, so consumers see the same keys for every node of a kind.
+func nodeAttributes(node Node) map[string]any {
+	switch node := node.(type) {
+	case *HeaderNode:
+		return map[string]any{"level": node.Level, "setext": node.IsSetext, "id": node.ID}
+	case *ImageNode:
+		return map[string]any{"text": node.LinkText, "link": node.Link, "title": node.Title}
+	case *TextNode:
+		return map[string]any{"content": node.Content}
+	case *LinkNode:
+		return map[string]any{"link": node.Link, "title": node.Title}
+	case *ListNode:
+		return map[string]any{"ordered": node.IsOrdered, "tight": node.IsTight, "start": node.Start, "marker": node.Marker}
+	case *ListItemNode:
+		return map[string]any{"checked": node.Checked}
+	case *InlineCodeBlockNode:
+		return map[string]any{"content": node.Content, "info": node.Info, "fenced": node.IsFenced}
+	case *InlineCodeNode:
+		return map[string]any{"content": node.Content}
+	case *HtmlBlockNode:
+		return map[string]any{"content": node.Content}
+	case *HtmlInlineNode:
+		return map[string]any{"content": node.Content}
+	case *TableRowNode:
+		return map[string]any{"header": node.IsHeader}
+	case *TableCellNode:
+		return map[string]any{"alignment": node.Alignment, "header": node.IsHeader}
+	case *FootnoteReferenceNode:
+		return map[string]any{"label": node.Label, "index": node.Index, "occurrence": node.Occurrence}
+	case *FootnoteDefinitionNode:
+		return map[string]any{"label": node.Label, "index": node.Index, "references": node.References}
+	case *DefinitionNode:
+		return map[string]any{"tight": node.IsTight}
+	default:
+		return nil
+	}
+}
+
+// DecodeJSON rebuilds a document from the output of EncodeJSON.
+func DecodeJSON(data []byte) (*DocumentNode, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var in jsonDocument
+	if err := decoder.Decode(&in); err != nil {
+		return nil, err
+	}
+	if in.Version != JSONVersion {
+		return nil, fmt.Errorf("unsupported JSON version %d, expected %d", in.Version, JSONVersion)
+	}
+	if in.Document == nil {
+		return nil, fmt.Errorf("missing document")
+	}
+
+	node, err := decodeNode(in.Document)
+	if err != nil {
+		return nil, err
+	}
+
+	document, ok := node.(*DocumentNode)
+	if !ok {
+		return nil, fmt.Errorf("expected a Document node at the root, found %s", in.Document.Kind)
+	}
+
+	for _, footnote := range in.Document.Footnotes {
+		node, err := decodeNode(footnote)
+		if err != nil {
+			return nil, err
+		}
+
+		definition, ok := node.(*FootnoteDefinitionNode)
+		if !ok {
+			return nil, fmt.Errorf("expected a FootnoteDefinition node among the footnotes, found %s", footnote.Kind)
+		}
+		document.Footnotes = append(document.Footnotes, definition)
+	}
+
+	if in.Document.FrontMatter != nil {
+		var frontMatter FrontMatter
+		frontMatter.Format = in.Document.FrontMatter.Format
+		frontMatter.Raw = in.Document.FrontMatter.Raw
+		frontMatter.Fields, _ = decodeNumbers(in.Document.FrontMatter.Fields).(map[string]any)
+		document.FrontMatter = &frontMatter
+	}
+
+	SetParents(document)
+	return document, nil
+}
+
+func decodeNode(in *jsonNode) (Node, error) {
+	if in == nil {
+		return nil, fmt.Errorf("missing node")
+	}
+	if in.Kind == "" {
+		return nil, fmt.Errorf("missing node kind")
+	}
+
+	var children []Node
+	for _, child := range in.Children {
+		node, err := decodeNode(child)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, node)
+	}
+
+	var attributes jsonAttributes
+	attributes.kind = in.Kind
+	attributes.values = in.Attributes
+
+	var node Node
+	switch in.Kind {
+	case DocumentKind.String():
+		node = &DocumentNode{Nodes: children}
+	case HeaderKind.String():
+		header := &HeaderNode{Level: attributes.intValue("level"), Content: children, IsSetext: attributes.boolValue("setext"), ID: attributes.stringValue("id")}
+		if attributes.err == nil && (header.Level < 1 || header.Level > 6) {
+			return nil, fmt.Errorf("%s node: level %d is not between 1 and 6", in.Kind, header.Level)
+		}
+		node = header
+	case ParagraphKind.String():
+		node = &ParagraphNode{Content: children}
+	case ImageKind.String():
+		node = &ImageNode{LinkText: attributes.stringValue("text"), Link: attributes.stringValue("link"), Title: attributes.stringValue("title")}
+	case ItalicKind.String():
+		node = &ItalicNode{Nodes: children}
+	case TextKind.String():
+		node = &TextNode{Content: attributes.stringValue("content")}
+	case BoldKind.String():
+		node = &BoldNode{Nodes: children}
+	case StrikethroughKind.String():
+		node = &StrikethroughNode{Nodes: children}
+	case LinkKind.String():
+		node = &LinkNode{Nodes: children, Link: attributes.stringValue("link"), Title: attributes.stringValue("title")}
+	case ListKind.String():
+		list := &ListNode{Nodes: children, IsOrdered: attributes.boolValue("ordered"), IsTight: attributes.boolValue("tight"), Start: attributes.intValue("start"), Marker: attributes.stringValue("marker")}
+		if attributes.err == nil && list.Start < 0 {
+			return nil, fmt.Errorf("%s node: negative start %d", in.Kind, list.Start)
+		}
+		node = list
+	case ListItemKind.String():
+		// checked is null for items that are not tasks
+		item := &ListItemNode{Nodes: children}
+		switch checked := in.Attributes["checked"].(type) {
+		case nil:
+		case bool:
+			item.Checked = &checked
+		default:
+			return nil, fmt.Errorf("%s node: attribute \"checked\" is not a boolean or null", in.Kind)
+		}
+		node = item
+	case BlockQuoteKind.String():
+		node = &BlockQuoteNode{Nodes: children}
+	case InlineCodeBlockKind.String():
+		node = &InlineCodeBlockNode{Content: attributes.stringValue("content"), Info: attributes.stringValue("info"), IsFenced: attributes.boolValue("fenced")}
+	case InlineCodeKind.String():
+		node = &InlineCodeNode{Content: attributes.stringValue("content")}
+	case HtmlBlockKind.String():
+		node = &HtmlBlockNode{Content: attributes.stringValue("content")}
+	case HtmlInlineKind.String():
+		node = &HtmlInlineNode{Content: attributes.stringValue("content")}
+	case TableKind.String():
+		node = &TableNode{Nodes: children}
+	case TableRowKind.String():
+		node = &TableRowNode{Nodes: children, IsHeader: attributes.boolValue("header")}
+	case TableCellKind.String():
+		cell := &TableCellNode{Content: children, Alignment: attributes.stringValue("alignment"), IsHeader: attributes.boolValue("header")}
+		switch cell.Alignment {
+		case "", "left", "center", "right":
+		default:
+			return nil, fmt.Errorf("%s node: unknown alignment %q", in.Kind, cell.Alignment)
+		}
+		node = cell
+	case FootnoteReferenceKind.String():
+		node = &FootnoteReferenceNode{Label: attributes.stringValue("label"), Index: attributes.intValue("index"), Occurrence: attributes.intValue("occurrence")}
+	case FootnoteDefinitionKind.String():
+		node = &FootnoteDefinitionNode{Label: attributes.stringValue("label"), Nodes: children, Index: attributes.intValue("index"), References: attributes.intValue("references")}
+	case DefinitionListKind.String():
+		node = &DefinitionListNode{Nodes: children}
+	case TermKind.String():
+		node = &TermNode{Content: children}
+	case DefinitionKind.String():
+		node = &DefinitionNode{Nodes: children, IsTight: attributes.boolValue("tight")}
+	case HorizontalRuleKind.String():
+		node = &HorizontalRuleNode{}
+	case NewLineKind.String():
+		node = &NewLineNode{}
+	case LineBreakKind.String():
+		node = &LineBreakNode{}
+	default:
+		return nil, fmt.Errorf("unknown node kind %q", in.Kind)
+	}
+
+	if attributes.err != nil {
+		return nil, attributes.err
+	}
+
+	node.base().span = in.Span
+	return node, nil
+}
+
+// jsonAttributes reads the attributes of one node. EncodeJSON writes every
+// attribute of a kind, so the first one missing or of the wrong type is
+// kept as the error for the node.
+type jsonAttributes struct {
+	kind   string
+	values map[string]any
+	err    error
+}
+
+func (a *jsonAttributes) fail(key string, expected string) {
+	if a.err != nil {
+		return
+	}
+
+	if _, ok := a.values[key]; !ok {
+		a.err = fmt.Errorf("%s node: missing attribute %q", a.kind, key)
+	} else {
+		a.err = fmt.Errorf("%s node: attribute %q is not %s", a.kind, key, expected)
+	}
+}
+
+func (a *jsonAttributes) stringValue(key string) string {
+	value, ok := a.values[key].(string)
+	if !ok {
+		a.fail(key, "a string")
+	}
+	return value
+}
+
+func (a *jsonAttributes) boolValue(key string) bool {
+	value, ok := a.values[key].(bool)
+	if !ok {
+		a.fail(key, "a boolean")
+	}
+	return value
+}
+
+func (a *jsonAttributes) intValue(key string) int {
+	number, _ := a.values[key].(json.Number)
+	value, err := strconv.ParseInt(string(number), 10, 32)
+	if err != nil {
+		a.fail(key, "an integer")
+	}
+	return int(value)
+}
+
+// decodeNumbers turns the numbers of decoded front matter back into the
+// int64 and float64 values the front matter parsers produce.
+func decodeNumbers(value any) any {
+	switch value := value.(type) {
+	case json.Number:
+		if number, err := value.Int64(); err == nil {
+			return number
+		}
+		number, _ := value.Float64()
+		return number
+	case map[string]any:
+		for key, item := range value {
+			value[key] = decodeNumbers(item)
+		}
+		return value
+	case []any:
+		for i, item := range value {
+			value[i] = decodeNumbers(item)
+		}
+		return value
+	default:
+		return value
+	}
+}
@@ -0,0 +1,181 @@
+package parse_test
+
+import (
+	gen "allium/src/convert"
+	"allium/src/parse"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+	}{
+		{
+			name:     "headings and inlines",
+			markdown: "# *a* **b** ~~c~~\n\nSetext\n======\n\nText with `code`, [a link](/url \"title\") and ![an image](/img.png).  \nAfter a break <span>html</span>\n",
+		},
+		{
+			name:     "lists",
+			markdown: "3) a\n4) b\n\n- [x] done\n- [ ] todo\n\n* loose\n\n* list\n",
+		},
+		{
+			name:     "blocks",
+			markdown: "> quote\n\n```go\nfunc main() {}\n```\n\n    indented\n\n<div>\nhtml\n</div>\n\n---\n",
+		},
+		{
+			name:     "tables",
+			markdown: "| a | b | c | d |\n| :- | :-: | -: | - |\n| 1 | 2 | 3 | 4 |\n",
+		},
+		{
+			name:     "footnotes and definitions",
+			markdown: "A note[^1] and again[^1].\n\n[^1]: The note.\n\nTerm\n: Definition\n\nOther\n\n: Loose\n",
+		},
+		{
+			name:     "front matter",
+			markdown: "---\ntitle: Page\ncount: 3\nratio: 0.5\ntags:\n  - a\n  - b\n---\n\nBody\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			document := parseMarkdown(test.markdown, allExtensions)
+			encoded, err := parse.EncodeJSON(document)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := parse.DecodeJSON(encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			reencoded, err := parse.EncodeJSON(decoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(encoded, reencoded) {
+				t.Errorf("re-encoded JSON differs:\n%s\nwant:\n%s", reencoded, encoded)
+			}
+
+			generator := gen.NewGenerator(document)
+			decodedGenerator := gen.NewGenerator(decoded)
+			if html, decodedHtml := generator.Html(), decodedGenerator.Html(); decodedHtml != html {
+				t.Errorf("decoded document renders %q, want %q", decodedHtml, html)
+			}
+		})
+	}
+}
+
+func TestDecodeJSONRejectsMalformedInput(t *testing.T) {
+	// wrap puts a node inside a document of the current version
+	wrap := func(node string) string {
+		return `{"version": 1, "document": {"kind": "Document", "children": [` + node + `]}}`
+	}
+
+	tests := []struct {
+		name  string
+		json  string
+		error string
+	}{
+		{
+			name:  "syntax",
+			json:  `{"version": 1,`,
+			error: "unexpected EOF",
+		},
+		{
+			name:  "version",
+			json:  `{"version": 2, "document": {"kind": "Document"}}`,
+			error: "unsupported JSON version 2",
+		},
+		{
+			name:  "missing document",
+			json:  `{"version": 1}`,
+			error: "missing document",
+		},
+		{
+			name:  "root kind",
+			json:  `{"version": 1, "document": {"kind": "Paragraph"}}`,
+			error: "expected a Document node at the root",
+		},
+		{
+			name:  "unknown kind",
+			json:  wrap(`{"kind": "Widget"}`),
+			error: `unknown node kind "Widget"`,
+		},
+		{
+			name:  "missing kind",
+			json:  wrap(`{"attributes": {"content": "a"}}`),
+			error: "missing node kind",
+		},
+		{
+			name:  "null child",
+			json:  wrap(`null`),
+			error: "missing node",
+		},
+		{
+			name:  "heading level too high",
+			json:  wrap(`{"kind": "Header", "attributes": {"level": 7, "setext": false, "id": ""}}`),
+			error: "level 7 is not between 1 and 6",
+		},
+		{
+			name:  "negative heading level",
+			json:  wrap(`{"kind": "Header", "attributes": {"level": -1, "setext": false, "id": ""}}`),
+			error: "level -1 is not between 1 and 6",
+		},
+		{
+			name:  "fractional heading level",
+			json:  wrap(`{"kind": "Header", "attributes": {"level": 1.5, "setext": false, "id": ""}}`),
+			error: `attribute "level" is not an integer`,
+		},
+		{
+			name:  "missing heading level",
+			json:  wrap(`{"kind": "Header", "attributes": {"setext": false, "id": ""}}`),
+			error: `Header node: missing attribute "level"`,
+		},
+		{
+			name:  "unknown alignment",
+			json:  wrap(`{"kind": "Table", "children": [{"kind": "TableRow", "attributes": {"header": true}, "children": [{"kind": "TableCell", "attributes": {"alignment": "middle", "header": true}}]}]}`),
+			error: `unknown alignment "middle"`,
+		},
+		{
+			name:  "negative list start",
+			json:  wrap(`{"kind": "List", "attributes": {"ordered": true, "tight": true, "start": -3, "marker": "."}}`),
+			error: "negative start -3",
+		},
+		{
+			name:  "missing text content",
+			json:  wrap(`{"kind": "Paragraph", "children": [{"kind": "Text"}]}`),
+			error: `Text node: missing attribute "content"`,
+		},
+		{
+			name:  "mistyped link",
+			json:  wrap(`{"kind": "Paragraph", "children": [{"kind": "Link", "attributes": {"link": 3, "title": ""}}]}`),
+			error: `Link node: attribute "link" is not a string`,
+		},
+		{
+			name:  "mistyped checkbox",
+			json:  wrap(`{"kind": "List", "attributes": {"ordered": false, "tight": true, "start": 0, "marker": "-"}, "children": [{"kind": "ListItem", "attributes": {"checked": "yes"}}]}`),
+			error: `attribute "checked" is not a boolean or null`,
+		},
+		{
+			name:  "footnote kind",
+			json:  `{"version": 1, "document": {"kind": "Document", "footnotes": [{"kind": "Paragraph"}]}}`,
+			error: "expected a FootnoteDefinition node among the footnotes",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parse.DecodeJSON([]byte(test.json))
+			if err == nil {
+				t.Fatalf("expected an error containing %q", test.error)
+			}
+			if !strings.Contains(err.Error(), test.error) {
+				t.Errorf("got error %q, want it to contain %q", err, test.error)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package parse
+
+// Kind and Children for each node. Children returns the inline content of
+// leaf blocks, and the document leaves out its footnote definitions, which
+// are kept in DocumentNode.Footnotes.
+
+func (n *DocumentNode) Kind() NodeKind   { return DocumentKind }
+func (n *DocumentNode) Children() []Node { return n.Nodes }
+
+func (n *HeaderNode) Kind() NodeKind   { return HeaderKind }
+func (n *HeaderNode) Children() []Node { return n.Content }
+
+func (n *ParagraphNode) Kind() NodeKind   { return ParagraphKind }
+func (n *ParagraphNode) Children() []Node { return n.Content }
+
+func (n *ImageNode) Kind() NodeKind   { return ImageKind }
+func (n *ImageNode) Children() []Node { return nil }
+
+func (n *ItalicNode) Kind() NodeKind   { return ItalicKind }
+func (n *ItalicNode) Children() []Node { return n.Nodes }
+
+func (n *TextNode) Kind() NodeKind   { return TextKind }
+func (n *TextNode) Children() []Node { return nil }
+
+func (n *BoldNode) Kind() NodeKind   { return BoldKind }
+func (n *BoldNode) Children() []Node { return n.Nodes }
+
+func (n *StrikethroughNode) Kind() NodeKind   { return StrikethroughKind }
+func (n *StrikethroughNode) Children() []Node { return n.Nodes }
+
+func (n *LinkNode) Kind() NodeKind   { return LinkKind }
+func (n *LinkNode) Children() []Node { return n.Nodes }
+
+func (n *ListNode) Kind() NodeKind   { return ListKind }
+func (n *ListNode) Children() []Node { return n.Nodes }
+
+func (n *ListItemNode) Kind() NodeKind   { return ListItemKind }
+func (n *ListItemNode) Children() []Node { return n.Nodes }
+
+func (n *BlockQuoteNode) Kind() NodeKind   { return BlockQuoteKind }
+func (n *BlockQuoteNode) Children() []Node { return n.Nodes }
+
+func (n *InlineCodeBlockNode) Kind() NodeKind   { return InlineCodeBlockKind }
+func (n *InlineCodeBlockNode) Children() []Node { return nil }
+
+func (n *InlineCodeNode) Kind() NodeKind   { return InlineCodeKind }
+func (n *InlineCodeNode) Children() []Node { return nil }
+
+func (n *HtmlBlockNode) Kind() NodeKind   { return HtmlBlockKind }
+func (n *HtmlBlockNode) Children() []Node { return nil }
+
+func (n *HtmlInlineNode) Kind() NodeKind   { return HtmlInlineKind }
+func (n *HtmlInlineNode) Children() []Node { return nil }
+
+func (n *TableNode) Kind() NodeKind   { return TableKind }
+func (n *TableNode) Children() []Node { return n.Nodes }
+
+func (n *TableRowNode) Kind() NodeKind   { return TableRowKind }
+func (n *TableRowNode) Children() []Node { return n.Nodes }
+
+func (n *TableCellNode) Kind() NodeKind   { return TableCellKind }
+func (n *TableCellNode) Children() []Node { return n.Content }
+
+func (n *FootnoteReferenceNode) Kind() NodeKind   { return FootnoteReferenceKind }
+func (n *FootnoteReferenceNode) Children() []Node { return nil }
+
+func (n *FootnoteDefinitionNode) Kind() NodeKind   { return FootnoteDefinitionKind }
+func (n *FootnoteDefinitionNode) Children() []Node { return n.Nodes }
+
+func (n *DefinitionListNode) Kind() NodeKind   { return DefinitionListKind }
+func (n *DefinitionListNode) Children() []Node { return n.Nodes }
+
+func (n *TermNode) Kind() NodeKind   { return TermKind }
+func (n *TermNode) Children() []Node { return n.Content }
+
+func (n *DefinitionNode) Kind() NodeKind   { return DefinitionKind }
+func (n *DefinitionNode) Children() []Node { return n.Nodes }
+
+func (n *HorizontalRuleNode) Kind() NodeKind   { return HorizontalRuleKind }
+func (n *HorizontalRuleNode) Children() []Node { return nil }
+
+func (n *NewLineNode) Kind() NodeKind   { return NewLineKind }
+func (n *NewLineNode) Children() []Node { return nil }
+
+func (n *LineBreakNode) Kind() NodeKind   { return LineBreakKind }
+func (n *LineBreakNode) Children() []Node { return nil }
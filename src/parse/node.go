@@ -1,69 +1,325 @@
 package parse
 
-type NodeType int
+import "fmt"
+
+// NodeKind identifies the type of a node without a type switch.
+type NodeKind int
 
 const (
-	GenericNodeType NodeType = iota
-	HeaderNodeType
+	DocumentKind NodeKind = iota
+	HeaderKind
+	ParagraphKind
+	ImageKind
+	ItalicKind
+	TextKind
+	BoldKind
+	StrikethroughKind
+	LinkKind
+	ListKind
+	ListItemKind
+	BlockQuoteKind
+	InlineCodeBlockKind
+	InlineCodeKind
+	HtmlBlockKind
+	HtmlInlineKind
+	TableKind
+	TableRowKind
+	TableCellKind
+	FootnoteReferenceKind
+	FootnoteDefinitionKind
+	DefinitionListKind
+	TermKind
+	DefinitionKind
+	HorizontalRuleKind
+	NewLineKind
+	LineBreakKind
 )
 
-type NodeInterface any
+var nodeKindNames = [...]string{
+	DocumentKind:           "Document",
+	HeaderKind:             "Header",
+	ParagraphKind:          "Paragraph",
+	ImageKind:              "Image",
+	ItalicKind:             "Italic",
+	TextKind:               "Text",
+	BoldKind:               "Bold",
+	StrikethroughKind:      "Strikethrough",
+	LinkKind:               "Link",
+	ListKind:               "List",
+	ListItemKind:           "ListItem",
+	BlockQuoteKind:         "BlockQuote",
+	InlineCodeBlockKind:    "InlineCodeBlock",
+	InlineCodeKind:         "InlineCode",
+	HtmlBlockKind:          "HtmlBlock",
+	HtmlInlineKind:         "HtmlInline",
+	TableKind:              "Table",
+	TableRowKind:           "TableRow",
+	TableCellKind:          "TableCell",
+	FootnoteReferenceKind:  "FootnoteReference",
+	FootnoteDefinitionKind: "FootnoteDefinition",
+	DefinitionListKind:     "DefinitionList",
+	TermKind:               "Term",
+	DefinitionKind:         "Definition",
+	HorizontalRuleKind:     "HorizontalRule",
+	NewLineKind:            "NewLine",
+	LineBreakKind:          "LineBreak",
+}
+
+func (k NodeKind) String() string {
+	if k < 0 || int(k) >= len(nodeKindNames) {
+		return fmt.Sprintf("NodeKind(%d)", int(k))
+	}
+	return nodeKindNames[k]
+}
+
+// Position is a 1-based line and byte column in the source.
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Span is the source range a node was parsed from, with End pointing at
+// its last character.
+type Span struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Node is implemented by every node of the tree. Parent is nil for the
+// document and for nodes that have not been attached to one.
+type Node interface {
+	Kind() NodeKind
+	Children() []Node
+	Span() Span
+	Parent() Node
+
+	base() *nodeBase
+}
+
+// nodeBase holds what every node shares and is embedded in each of them.
+type nodeBase struct {
+	parent Node
+	span   Span
+}
+
+func (n *nodeBase) Parent() Node {
+	return n.parent
+}
+
+func (n *nodeBase) Span() Span {
+	return n.span
+}
+
+func (n *nodeBase) base() *nodeBase {
+	return n
+}
+
+// SetParents points the parent of every node below node back up the tree.
+// The parser does this itself, it is only needed after editing a tree.
+func SetParents(node Node) {
+	for _, child := range node.Children() {
+		child.base().parent = node
+		SetParents(child)
+	}
 
+	if document, ok := node.(*DocumentNode); ok {
+		for _, footnote := range document.Footnotes {
+			footnote.parent = document
+			SetParents(footnote)
+		}
+	}
+}
+
+// DocumentNode is the root of a parsed document. Footnote definitions are
+// kept apart from the body, ordered by their number, and FrontMatter is nil
+// unless the document starts with a metadata block.
+type DocumentNode struct {
+	nodeBase
+
+	Nodes       []Node
+	Footnotes   []*FootnoteDefinitionNode
+	FrontMatter *FrontMatter
+}
+
+// HeaderNode is an ATX or setext heading. ID is only set when the heading
+// gives one explicitly with a trailing {#custom-id}.
 type HeaderNode struct {
-	Level   int
-	Content []NodeInterface
+	nodeBase
+
+	Level    int
+	Content  []Node
+	IsSetext bool
+	ID       string
 }
 
 type ParagraphNode struct {
-	Content []NodeInterface
+	nodeBase
+
+	Content []Node
 }
 
 type ImageNode struct {
+	nodeBase
+
 	LinkText string
 	Link     string
+	Title    string
 }
 
-type NoNode struct{}
-
 type ItalicNode struct {
-	Nodes []NodeInterface
+	nodeBase
+
+	Nodes []Node
 }
 
 type TextNode struct {
+	nodeBase
+
 	Content string
 }
 
 type BoldNode struct {
-	Nodes []NodeInterface
+	nodeBase
+
+	Nodes []Node
+}
+
+type StrikethroughNode struct {
+	nodeBase
+
+	Nodes []Node
 }
 
 type LinkNode struct {
-	LinkText string
-	Link     string
+	nodeBase
+
+	Nodes []Node
+	Link  string
+	Title string
 }
 
 type ListNode struct {
-	Nodes     []NodeInterface
+	nodeBase
+
+	Nodes     []Node
 	IsOrdered bool
+	IsTight   bool
+	Start     int
+	Marker    string
 }
 
+// ListItemNode is a single list item. Checked is only set for task list
+// items, where it records whether the box is ticked.
 type ListItemNode struct {
-	Nodes []NodeInterface
+	nodeBase
+
+	Nodes   []Node
+	Checked *bool
 }
 
 type BlockQuoteNode struct {
-	Nodes []NodeInterface
+	nodeBase
+
+	Nodes []Node
 }
 
 type InlineCodeBlockNode struct {
-	Content string
+	nodeBase
+
+	Content  string
+	Info     string
+	IsFenced bool
 }
 
 type InlineCodeNode struct {
+	nodeBase
+
+	Content string
+}
+
+type HtmlBlockNode struct {
+	nodeBase
+
+	Content string
+}
+
+type HtmlInlineNode struct {
+	nodeBase
+
 	Content string
 }
 
-type HorizontalRuleNode struct{}
+type TableNode struct {
+	nodeBase
+
+	Nodes []Node
+}
+
+type TableRowNode struct {
+	nodeBase
+
+	Nodes    []Node
+	IsHeader bool
+}
+
+// TableCellNode holds the inline content of a single cell. Alignment is
+// "left", "center", "right" or empty when the column has none.
+type TableCellNode struct {
+	nodeBase
+
+	Content   []Node
+	Alignment string
+	IsHeader  bool
+}
+
+// FootnoteReferenceNode refers to a footnote by label. Index is the number
+// of the footnote and Occurrence counts the references made to it so far.
+type FootnoteReferenceNode struct {
+	nodeBase
+
+	Label      string
+	Index      int
+	Occurrence int
+}
+
+// FootnoteDefinitionNode holds the content of a footnote. Index is zero for
+// definitions that are never referenced.
+type FootnoteDefinitionNode struct {
+	nodeBase
+
+	Label      string
+	Nodes      []Node
+	Index      int
+	References int
+}
+
+type DefinitionListNode struct {
+	nodeBase
+
+	Nodes []Node
+}
+
+type TermNode struct {
+	nodeBase
 
-type WhiteSpaceNode struct{}
-type NewLineNode struct{}
+	Content []Node
+}
+
+type DefinitionNode struct {
+	nodeBase
+
+	Nodes   []Node
+	IsTight bool
+}
+
+type HorizontalRuleNode struct {
+	nodeBase
+}
+
+type NewLineNode struct {
+	nodeBase
+}
+
+type LineBreakNode struct {
+	nodeBase
+}
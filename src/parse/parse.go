@@ -2,409 +2,290 @@ package parse
 
 import (
 	"allium/src/lex"
-	"math"
+	"strings"
 )
 
-type Parser struct {
-	Nodes   []NodeInterface
-	Tokens  []lex.Token
-	Current int
-}
-
-func (p *Parser) Parse() []NodeInterface {
-	for !p.isEnd() && !p.match(lex.Eof) {
-		node := p.parseBlock()
-		p.Nodes = append(p.Nodes, node)
-	}
+const codeIndent = 4
 
-	return p.Nodes
+// Parser turns the token stream into a document in two phases. The block
+// phase consumes the source line by line and builds a tree of container and
+// leaf blocks, the inline phase then parses the raw text of every leaf once
+// all link reference definitions are known.
+type Parser struct {
+	Tokens     []lex.Token
+	Current    int
+	Extensions Extension
+
+	lines          []string
+	lineNumber     int
+	currentLine    string
+	lastLineLength int
+
+	offset               int
+	column               int
+	nextNonspace         int
+	nextNonspaceColumn   int
+	indent               int
+	indented             bool
+	blank                bool
+	partiallyConsumedTab bool
+
+	root                 *block
+	tip                  *block
+	oldTip               *block
+	lastMatchedContainer *block
+	allClosed            bool
+
+	references map[string]linkReference
+	footnotes  *footnoteIndex
 }
 
-// func (p *Parser) parseOrderedList() NodeInterface {
-// 	numberToken := p.currentToken()
-// 	p.advance()
-// 	p.advance()
-
-// 	for
-
-// 	var node OrderedListNode
-// 	return node
-// }
-
-func (p *Parser) parseBlock() NodeInterface {
+func (p *Parser) Parse() *DocumentNode {
+	p.readLines()
 
-	switch p.currentToken().TokenKind {
-	case lex.Number:
-		if p.peek().TokenKind == lex.Dot {
-			return p.parseUnorderedListGroup()
-		}
-		return p.parseParagraph()
-	case lex.Hashtag:
-		return p.parseHeader()
-	case lex.LeftSquareBracket, lex.Exclamation:
-		return p.parseLink()
-	case lex.Star:
-		if p.peek().TokenKind == lex.WhiteSpace {
-			return p.parseUnorderedListGroup()
-		}
-		if p.peek().TokenKind == lex.Star {
-			p.advance()
+	p.references = make(map[string]linkReference)
+	p.footnotes = newFootnoteIndex()
+	p.root = newBlock(documentBlock, 1, 1)
+	p.tip = p.root
+	p.oldTip = p.root
+	p.lastMatchedContainer = p.root
 
-			if p.peek().TokenKind == lex.Star {
-				p.recede()
-				return p.parseHorizontalRule()
-			} else {
-				p.recede()
-			}
-		}
-		return p.parseParagraph()
-	case lex.Minus:
-		if p.peek().TokenKind == lex.WhiteSpace {
-			return p.parseUnorderedListGroup()
-		}
-		return p.parseHorizontalRule()
-	case lex.GreaterThan:
-		return p.parseBlockQuote()
-	case lex.BackTick:
-		if p.peek().TokenKind != lex.BackTick {
-			return p.parseInlineCode()
-		}
-		return p.parseInlineCodeBlock()
-	default:
-		return p.parseParagraph()
+	var frontMatter *FrontMatter
+	if p.hasExtension(FrontMatterExtension) {
+		frontMatter = p.parseFrontMatter()
 	}
-}
 
-func (p *Parser) parseInlineCodeBlock() NodeInterface {
-	p.advance()
-	if p.match(lex.BackTick) {
-		p.advance()
-
-		if p.match(lex.BackTick) {
-			p.advance()
-
-			content := ""
-			for !p.isEnd() && !p.match(lex.BackTick) {
-				content += p.currentToken().Value
-				p.advance()
-			}
-
-			p.advance()
-			p.advance()
-			p.advance()
-
-			var node InlineCodeBlockNode
-			node.Content = content
-
-			return node
-		} else {
-			p.recede()
-			p.recede()
-		}
+	for _, line := range p.lines[p.lineNumber:] {
+		p.incorporateLine(line)
 	}
-
-	return p.parseParagraph()
-}
-
-func (p *Parser) parseInlineCode() NodeInterface {
-	p.advance()
-
-	content := ""
-	for !p.isEnd() && !p.match(lex.BackTick) {
-		content += p.currentToken().Value
-		p.advance()
+	for p.tip != nil {
+		p.finalize(p.tip, len(p.lines))
 	}
-	p.advance()
 
-	var node InlineCodeNode
-	node.Content = content
-	return node
-}
-
-func (p *Parser) parseBlockQuote() NodeInterface {
-	p.advance()
-
-	var nodes []NodeInterface
-	for !p.isEnd() && !p.isNodeEnd() {
-		node := p.parseInline()
-		nodes = append(nodes, node)
-	}
+	document := p.buildNode(p.root).(*DocumentNode)
+	document.Footnotes = p.footnotes.sorted()
+	document.FrontMatter = frontMatter
+	SetParents(document)
 
-	var node BlockQuoteNode
-	node.Nodes = nodes
-
-	return node
-}
-
-func (p *Parser) isHorizontalRuleToken() bool {
-	return p.match(lex.Star) || p.match(lex.Minus)
+	return document
 }
 
-func (p *Parser) parseHorizontalRule() NodeInterface {
-	p.advance()
-
-	if p.isHorizontalRuleToken() {
-		p.advance()
+// readLines joins the token stream back into source lines. The lexer has
+// already normalised every line ending to a single NewLine token.
+func (p *Parser) readLines() {
+	var line strings.Builder
+	pending := false
 
-		if p.isHorizontalRuleToken() {
-			p.advance()
-			return HorizontalRuleNode{}
+	for !p.isEnd() && !p.match(lex.Eof) {
+		if p.match(lex.NewLine) {
+			p.lines = append(p.lines, line.String())
+			line.Reset()
+			pending = false
 		} else {
-			p.recede()
-			p.recede()
-		}
-	} else {
-		p.recede()
-	}
-
-	return p.parseParagraph()
-}
-
-func (p *Parser) isListToken() bool {
-	return p.match(lex.Star) || p.match(lex.Minus) || p.match(lex.Number)
-}
-
-func (p *Parser) parseUnorderedListGroup() NodeInterface {
-	var list ListNode
-
-	isOrdered := false
-	if p.match(lex.Number) {
-		isOrdered = true
-	}
-
-	for !p.isEnd() && p.isListToken() && (p.peek().TokenKind == lex.WhiteSpace || p.peek().TokenKind == lex.Dot) {
-		listItem := p.parseUnorderedListNode()
-		list.Nodes = append(list.Nodes, listItem)
-
-		for !p.isEnd() && p.isNodeEnd() {
-			p.advance()
+			line.WriteString(p.currentToken().Value)
+			pending = true
 		}
-	}
-
-	list.IsOrdered = isOrdered
-	return list
-}
-
-func (p *Parser) parseUnorderedListNode() NodeInterface {
-	var node ListItemNode
-
-	if p.isListToken() {
 		p.advance()
 	}
 
-	if p.match(lex.Dot) {
-		p.advance()
+	if pending {
+		p.lines = append(p.lines, line.String())
 	}
 
-	if p.match(lex.WhiteSpace) {
-		p.advance()
+	for i, line := range p.lines {
+		p.lines[i] = strings.ReplaceAll(line, "\x00", "\uFFFD")
 	}
-
-	for !p.isEnd() && !p.isNodeEnd() {
-		token := p.parseInline()
-		if _, ok := token.(NoNode); !ok {
-			node.Nodes = append(node.Nodes, token)
-		}
-	}
-
-	return node
 }
 
-func (p *Parser) isNodeEnd() bool {
-	return p.match(lex.NewLine) || p.match(lex.CarriageReturn)
-}
-
-func (p *Parser) parseLink() NodeInterface {
-	isImage := p.match(lex.Exclamation)
-	if isImage {
-		p.advance()
-	}
-
-	p.advance()
-
-	linkText := ""
-	for !p.isEnd() && !p.match(lex.RightSquareBracket) {
-		linkText += p.currentToken().Value
-		p.advance()
-	}
-
-	link := ""
-	p.advance()
-	p.advance()
-
-	for !p.match(lex.RightParen) {
-		link += p.currentToken().Value
-		p.advance()
-	}
-	p.advance()
-
-	if isImage {
-		var node ImageNode
-		node.Link = link
-		node.LinkText = linkText
-
-		return node
-	}
-
-	var node LinkNode
-	node.LinkText = linkText
-	node.Link = link
-
-	return node
-}
-
-func (p *Parser) parseParagraph() NodeInterface {
-	if p.match(lex.CarriageReturn) {
-		p.advance()
-		p.advance()
-		return NoNode{}
-	}
-
-	var node ParagraphNode
-	var content []NodeInterface
+func (p *Parser) incorporateLine(line string) {
+	container := p.root
+	p.oldTip = p.tip
+	p.offset = 0
+	p.column = 0
+	p.blank = false
+	p.partiallyConsumedTab = false
+	p.lineNumber++
+	p.currentLine = line
+
+	// try to match the line against every open block, innermost last
+	allMatched := true
+	for container.lastChild() != nil && container.lastChild().open {
+		container = container.lastChild()
+		p.findNextNonspace()
+
+		switch p.continueBlock(container) {
+		case continueMatched:
+		case continueUnmatched:
+			allMatched = false
+			container = container.parent
+		case continueDone:
+			p.lastLineLength = len(line)
+			return
+		}
 
-	var lastNode NodeInterface
-	for !p.isEnd() && !p.match(lex.NewLine) {
-		if p.match(lex.Hashtag) {
+		if !allMatched {
 			break
 		}
+	}
+
+	p.allClosed = container == p.oldTip
+	p.lastMatchedContainer = container
 
-		currentNode := p.parseInline()
+	// paragraphs and tables take text lines but can still be interrupted by
+	// the start of another block
+	matchedLeaf := container.kind != paragraphBlock && container.kind != tableBlock && container.acceptsLines()
+	for !matchedLeaf {
+		p.findNextNonspace()
 
-		if _, ok := lastNode.(NewLineNode); ok {
-			if _, ok := currentNode.(NewLineNode); ok {
-				// p.recede()
+		started := startNone
+		for _, start := range blockStarts {
+			started = start(p, container)
+			if started != startNone {
 				break
 			}
 		}
 
-		content = append(content, currentNode)
-		lastNode = currentNode
-	}
-
-	for len(content) > 0 {
-		if _, ok := content[len(content)-1].(NewLineNode); ok {
-			content = content[:len(content)-1]
-		} else {
+		if started == startNone {
+			p.advanceNextNonspace()
 			break
 		}
-	}
 
-	filtered := make([]NodeInterface, 0, len(content))
-	for _, n := range content {
-		switch n.(type) {
-		case NewLineNode, NoNode:
-
-		default:
-			filtered = append(filtered, n)
+		container = p.tip
+		if started == startLeaf {
+			matchedLeaf = true
 		}
 	}
 
-	if len(filtered) == 0 {
-		return NoNode{}
-	}
+	// what remains of the line is text, either a lazy paragraph
+	// continuation or content for the innermost block
+	if !p.allClosed && !p.blank && p.tip.kind == paragraphBlock {
+		p.addLine()
+	} else {
+		p.closeUnmatchedBlocks()
 
-	node.Content = content
-	return node
-}
+		if container.acceptsLines() {
+			p.addLine()
 
-func (p *Parser) parseInline() NodeInterface {
-	switch p.currentToken().TokenKind {
-	case lex.Star, lex.Underscore:
-		return p.parseBoldItalic()
-	case lex.WhiteSpace:
-		p.advance()
-		return WhiteSpaceNode{}
-	case lex.NewLine, lex.CarriageReturn:
-		p.advance()
-		p.advance()
-		return NewLineNode{}
-	default:
-		content := p.currentToken().Value
-		p.advance()
-		return TextNode{Content: content}
+			if container.kind == htmlBlock && container.htmlBlockType >= 1 && container.htmlBlockType <= 5 &&
+				htmlBlockClose[container.htmlBlockType].MatchString(p.currentLine[p.offset:]) {
+				p.lastLineLength = len(line)
+				p.finalize(container, p.lineNumber)
+			}
+		} else if p.offset < len(line) && !p.blank {
+			p.addChild(paragraphBlock, p.offset)
+			p.advanceNextNonspace()
+			p.addLine()
+		}
 	}
-}
 
-func (p *Parser) isBoldItalicToken(token lex.Token) bool {
-	return token.TokenKind == lex.Star || token.TokenKind == lex.Underscore
+	p.lastLineLength = len(line)
 }
 
-func (p *Parser) parseBoldItalic() NodeInterface {
-	marker := p.currentToken().TokenKind
-	markerCount := 0
-
-	for !p.isEnd() && p.currentToken().TokenKind == marker {
-		markerCount++
-		p.advance()
+func (p *Parser) findNextNonspace() {
+	i := p.offset
+	columns := p.column
+
+	for i < len(p.currentLine) {
+		c := p.currentLine[i]
+		if c == ' ' {
+			i++
+			columns++
+		} else if c == '\t' {
+			i++
+			columns += 4 - (columns % 4)
+		} else {
+			break
+		}
 	}
 
-	var nodes []NodeInterface
-	for !p.isEnd() {
-		if p.currentToken().TokenKind == marker {
-			count := 0
-			start := p.Current
-			for !p.isEnd() && p.currentToken().TokenKind == marker {
-				count++
-				p.advance()
-			}
+	p.blank = i >= len(p.currentLine)
+	p.nextNonspace = i
+	p.nextNonspaceColumn = columns
+	p.indent = p.nextNonspaceColumn - p.column
+	p.indented = p.indent >= codeIndent
+}
 
-			if count == markerCount {
-				break
+func (p *Parser) advanceNextNonspace() {
+	p.offset = p.nextNonspace
+	p.column = p.nextNonspaceColumn
+	p.partiallyConsumedTab = false
+}
+
+// advanceOffset moves forward by count characters, or by count columns when
+// columns is set, in which case a tab may only be partially consumed.
+func (p *Parser) advanceOffset(count int, columns bool) {
+	for count > 0 && p.offset < len(p.currentLine) {
+		if p.currentLine[p.offset] == '\t' {
+			charsToTab := 4 - (p.column % 4)
+			if columns {
+				p.partiallyConsumedTab = charsToTab > count
+				charsToAdvance := min(charsToTab, count)
+				p.column += charsToAdvance
+				if !p.partiallyConsumedTab {
+					p.offset++
+				}
+				count -= charsToAdvance
 			} else {
-				p.Current = start
+				p.partiallyConsumedTab = false
+				p.column += charsToTab
+				p.offset++
+				count--
 			}
+		} else {
+			p.partiallyConsumedTab = false
+			p.offset++
+			p.column++
+			count--
 		}
-
-		child := p.parseInline()
-		nodes = append(nodes, child)
 	}
+}
+
+func (p *Parser) addLine() {
+	p.tip.source.lines = append(p.tip.source.lines, sourceLine{line: p.lineNumber, column: p.offset + 1, offset: p.tip.content.Len()})
 
-	var result NodeInterface
-	switch markerCount {
-	case 3:
-		result = BoldNode{Nodes: []NodeInterface{ItalicNode{Nodes: nodes}}}
-	case 2:
-		result = BoldNode{Nodes: nodes}
-	case 1:
-		result = ItalicNode{Nodes: nodes}
+	if p.partiallyConsumedTab {
+		p.offset++
+		charsToTab := 4 - (p.column % 4)
+		p.tip.content.WriteString(strings.Repeat(" ", charsToTab))
 	}
 
-	return result
+	p.tip.content.WriteString(p.currentLine[p.offset:])
+	p.tip.content.WriteString("\n")
 }
 
-func (p *Parser) parseHeader() NodeInterface {
-	level := 1
-
-	p.advance()
-	for p.match(lex.Hashtag) {
-		level++
-		p.advance()
-	}
-	if p.match(lex.WhiteSpace) {
-		p.advance()
+func (p *Parser) addChild(kind blockKind, offset int) *block {
+	for !p.tip.canContain(kind) {
+		p.finalize(p.tip, p.lineNumber-1)
 	}
 
-	var children []NodeInterface
-	for !p.isEnd() && !p.match(lex.NewLine) {
-		expr := p.parseInline()
+	child := newBlock(kind, p.lineNumber, offset+1)
+	p.tip.appendChild(child)
+	p.tip = child
 
-		if _, ok := expr.(NewLineNode); ok {
-			break
-		}
+	return child
+}
 
-		if p.match(lex.NewLine) {
-			break
-		}
+func (p *Parser) closeUnmatchedBlocks() {
+	if p.allClosed {
+		return
+	}
 
-		children = append(children, expr)
+	for p.oldTip != p.lastMatchedContainer {
+		parent := p.oldTip.parent
+		p.finalize(p.oldTip, p.lineNumber-1)
+		p.oldTip = parent
 	}
+	p.allClosed = true
+}
 
-	var node HeaderNode
-	node.Level = int(math.Min(float64(level), 6))
-	node.Content = children
+func (p *Parser) finalize(b *block, lineNumber int) {
+	above := b.parent
+	b.open = false
+	b.endLine = lineNumber
+	b.endColumn = p.lastLineLength
 
-	return node
+	p.finalizeBlock(b)
+	p.tip = above
 }
 
 func (p *Parser) currentToken() lex.Token {
@@ -422,22 +303,10 @@ func (p *Parser) advance() {
 	p.Current++
 }
 
-func (p *Parser) recede() {
-	p.Current--
-}
-
 func (p *Parser) isEnd() bool {
 	return p.Current >= len(p.Tokens)
 }
 
-func (p *Parser) peek() lex.Token {
-	p.advance()
-	next := p.currentToken()
-	p.recede()
-
-	return next
-}
-
 func NewParser(tokens []lex.Token) *Parser {
 	var parser Parser
 	parser.Tokens = tokens
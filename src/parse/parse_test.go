@@ -0,0 +1,145 @@
+package parse_test
+
+import (
+	gen "allium/src/convert"
+	"allium/src/lex"
+	"allium/src/parse"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const allExtensions = parse.TablesExtension | parse.StrikethroughExtension | parse.TaskListExtension |
+	parse.FootnotesExtension | parse.DefinitionListExtension | parse.FrontMatterExtension | parse.HeaderIdsExtension
+
+func parseMarkdown(source string, extensions parse.Extension) *parse.DocumentNode {
+	parser := parse.NewParser(lex.NewLexer(source).Tokenize())
+	parser.Extensions = extensions
+	return parser.Parse()
+}
+
+var headerId = regexp.MustCompile(`(<h[1-6]) id="[^"]*"`)
+
+// render converts source to HTML, leaving out the heading IDs that
+// CommonMark does not have.
+func render(source string, extensions parse.Extension) string {
+	generator := gen.NewGenerator(parseMarkdown(source, extensions))
+	return headerId.ReplaceAllString(generator.Html(), "$1")
+}
+
+func TestNestedListTightness(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		html     string
+	}{
+		{
+			name:     "blank line after nested list",
+			markdown: "- a\n  - b\n\n- c\n",
+			html:     "<ul>\n<li>\n<p>a</p>\n<ul>\n<li>b</li>\n</ul>\n</li>\n<li>\n<p>c</p>\n</li>\n</ul>\n",
+		},
+		{
+			name:     "paragraph after nested list",
+			markdown: "- foo\n  - bar\n\n  para of foo\n",
+			html:     "<ul>\n<li>\n<p>foo</p>\n<ul>\n<li>bar</li>\n</ul>\n<p>para of foo</p>\n</li>\n</ul>\n",
+		},
+		{
+			name:     "blank line after list",
+			markdown: "- a\n- b\n\npara\n",
+			html:     "<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n<p>para</p>\n",
+		},
+		{
+			name:     "blank line after indented code in item",
+			markdown: "1.  a\n\n        code\n\n    b\n",
+			html:     "<ol>\n<li>\n<p>a</p>\n<pre><code>code\n</code></pre>\n<p>b</p>\n</li>\n</ol>\n",
+		},
+	}
+
+	for _, test := range tests {
+		if html := render(test.markdown, 0); html != test.html {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.name, html, test.html)
+		}
+	}
+}
+
+func TestBlockSpanEnds(t *testing.T) {
+	tests := []struct {
+		markdown string
+		kind     parse.NodeKind
+		end      parse.Position
+	}{
+		{"- a\n- b\n\npara\n", parse.ListKind, parse.Position{Line: 2, Column: 3}},
+		{"- a\n  - b\n\n- c\n", parse.ListKind, parse.Position{Line: 4, Column: 3}},
+		{"    code\n\npara\n", parse.InlineCodeBlockKind, parse.Position{Line: 1, Column: 8}},
+		{"    code\n      more\n\n\npara\n", parse.InlineCodeBlockKind, parse.Position{Line: 2, Column: 10}},
+		{"```\ncode\n\n", parse.InlineCodeBlockKind, parse.Position{Line: 2, Column: 4}},
+		{"```\n\n\n", parse.InlineCodeBlockKind, parse.Position{Line: 1, Column: 3}},
+		{"a\n\n\n", parse.DocumentKind, parse.Position{Line: 1, Column: 1}},
+	}
+
+	for _, test := range tests {
+		node := parse.Find(parseMarkdown(test.markdown, 0), test.kind)
+		if node == nil {
+			t.Errorf("%q: no %s", test.markdown, test.kind)
+			continue
+		}
+		if end := node.Span().End; end != test.end {
+			t.Errorf("%q: %s ends at %d:%d, want %d:%d", test.markdown, test.kind, end.Line, end.Column, test.end.Line, test.end.Column)
+		}
+	}
+}
+
+// TestSpansEndOnLastCharacter checks that every node ends on a character of
+// its last line, never before its start or on the column before a line.
+func TestSpansEndOnLastCharacter(t *testing.T) {
+	documents := []string{
+		"- a\n- b\n\npara\n",
+		"    code\n\npara\n",
+		"```\ncode\n\n",
+		"> quote\n>\n> - item\n\n\n",
+		"Term\n: definition\n\n: another\n\nafter\n",
+		"text[^1]\n\n[^1]: note\n\n    more\n\n",
+		"| a | b | c |\n|---|---|---|\n| 1 |\n\n",
+		"- [x] done\n\n- [ ] *todo* `code` ~~struck~~\n\n",
+		"# Title {#id}\n\nSetext\n---\n\n",
+	}
+
+	for _, source := range documents {
+		lines := strings.Split(source, "\n")
+		parse.Walk(parseMarkdown(source, allExtensions), func(node parse.Node, entering bool) parse.WalkStatus {
+			if !entering {
+				return parse.WalkContinue
+			}
+
+			span := node.Span()
+			start, end := span.Start, span.End
+			switch {
+			case start.Line < 1 || start.Column < 1 || end.Line < 1 || end.Column < 1:
+				t.Errorf("%q: %s has span %d:%d-%d:%d", source, node.Kind(), start.Line, start.Column, end.Line, end.Column)
+			case end.Line < start.Line || (end.Line == start.Line && end.Column < start.Column):
+				t.Errorf("%q: %s ends at %d:%d before it starts at %d:%d", source, node.Kind(), end.Line, end.Column, start.Line, start.Column)
+			case end.Column > len(lines[end.Line-1])+1:
+				t.Errorf("%q: %s ends at %d:%d past the end of the line", source, node.Kind(), end.Line, end.Column)
+			}
+
+			return parse.WalkContinue
+		})
+	}
+}
+
+func TestFootnoteDefinitionWhitespace(t *testing.T) {
+	tests := []struct {
+		markdown string
+		note     string
+	}{
+		{"a[^1]\n\n[^1]: <div>note</div>\n", "<li id=\"fn-1\">\n<div>note</div>\n"},
+		{"a[^1]\n\n[^1]:\tnote\n", "<li id=\"fn-1\">\n<p>note "},
+		{"a[^1]\n\n[^1]:     note\n", "<li id=\"fn-1\">\n<p>note "},
+	}
+
+	for _, test := range tests {
+		if html := render(test.markdown, parse.FootnotesExtension); !strings.Contains(html, test.note) {
+			t.Errorf("%q: got\n%s\nwant it to contain\n%s", test.markdown, html, test.note)
+		}
+	}
+}
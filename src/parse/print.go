@@ -2,136 +2,244 @@ package parse
 
 import "fmt"
 
+func (n DocumentNode) Print(indent int) {
+	fmt.Printf("%-16s%sDocumentNode: \n", formatSpan(n.Span()), spaces(indent))
+	for _, child := range n.Nodes {
+		printNode(child, indent+2)
+	}
+	for _, footnote := range n.Footnotes {
+		printNode(footnote, indent+2)
+	}
+}
+
 func (n ParagraphNode) Print(indent int) {
-	fmt.Printf("%sParagraphNode: \n", spaces(indent))
+	fmt.Printf("%-16s%sParagraphNode: \n", formatSpan(n.Span()), spaces(indent))
 	for _, child := range n.Content {
 		printNode(child, indent+2)
 	}
 }
 
 func (n ItalicNode) Print(indent int) {
-	fmt.Printf("%sItalicNode: \n", spaces(indent))
+	fmt.Printf("%-16s%sItalicNode: \n", formatSpan(n.Span()), spaces(indent))
 	for _, child := range n.Nodes {
 		printNode(child, indent+2)
 	}
 }
 
 func (n BoldNode) Print(indent int) {
-	fmt.Printf("%sBoldNode: \n", spaces(indent))
+	fmt.Printf("%-16s%sBoldNode: \n", formatSpan(n.Span()), spaces(indent))
+	for _, child := range n.Nodes {
+		printNode(child, indent+2)
+	}
+}
+
+func (n StrikethroughNode) Print(indent int) {
+	fmt.Printf("%-16s%sStrikethroughNode: \n", formatSpan(n.Span()), spaces(indent))
 	for _, child := range n.Nodes {
 		printNode(child, indent+2)
 	}
 }
 
 func (n TextNode) Print(indent int) {
-	fmt.Printf("%sTextNode: '%s'\n", spaces(indent), n.Content)
+	fmt.Printf("%-16s%sTextNode: '%s'\n", formatSpan(n.Span()), spaces(indent), n.Content)
 }
 
 func (n HeaderNode) Print(indent int) {
-	fmt.Printf("%sHeaderNode (level %d):\n", spaces(indent), n.Level)
+	fmt.Printf("%-16s%sHeaderNode (level %d, id '%s'):\n", formatSpan(n.Span()), spaces(indent), n.Level, n.ID)
 	for _, child := range n.Content {
 		printNode(child, indent+2)
 	}
 }
 
-func (n WhiteSpaceNode) Print(indent int) {
-	fmt.Printf("%sWhiteSpaceNode: '%s'\n", spaces(indent), " ")
-}
-
 func (n NewLineNode) Print(indent int) {
-	fmt.Printf("%sNewLineNode: '%s'\n", spaces(indent), "\\n")
+	fmt.Printf("%-16s%sNewLineNode: '%s'\n", formatSpan(n.Span()), spaces(indent), "\\n")
 }
 
-func (n NoNode) Print(indent int) {
-	fmt.Printf("%sNoNode: '%s'\n", spaces(indent), "none")
+func (n LineBreakNode) Print(indent int) {
+	fmt.Printf("%-16s%sLineBreakNode: '%s'\n", formatSpan(n.Span()), spaces(indent), "\\n")
 }
 
 func (n HorizontalRuleNode) Print(indent int) {
-	fmt.Printf("%sHorizontalRuleNode: '%s'\n", spaces(indent), "---")
+	fmt.Printf("%-16s%sHorizontalRuleNode: '%s'\n", formatSpan(n.Span()), spaces(indent), "---")
 }
 
 func (n ListItemNode) Print(indent int) {
-	fmt.Printf("%sUnorderedListNode: \n", spaces(indent))
+	if n.Checked != nil {
+		fmt.Printf("%-16s%sListItemNode (checked %t):\n", formatSpan(n.Span()), spaces(indent), *n.Checked)
+	} else {
+		fmt.Printf("%-16s%sListItemNode: \n", formatSpan(n.Span()), spaces(indent))
+	}
 	for _, child := range n.Nodes {
 		printNode(child, indent+2)
 	}
 }
 
 func (n ListNode) Print(indent int) {
-	fmt.Printf("%sUnorderedList: \n", spaces(indent))
+	fmt.Printf("%-16s%sListNode (ordered %t, tight %t):\n", formatSpan(n.Span()), spaces(indent), n.IsOrdered, n.IsTight)
 	for _, child := range n.Nodes {
 		printNode(child, indent+2)
 	}
 }
 
 func (n LinkNode) Print(indent int) {
-	fmt.Printf("%sLinkNode: '%s' : '%s'\n", spaces(indent), n.LinkText, n.Link)
+	fmt.Printf("%-16s%sLinkNode: '%s' '%s'\n", formatSpan(n.Span()), spaces(indent), n.Link, n.Title)
+	for _, child := range n.Nodes {
+		printNode(child, indent+2)
+	}
 }
 
 func (n ImageNode) Print(indent int) {
-	fmt.Printf("%sImageNode: '%s' : '%s'\n", spaces(indent), n.LinkText, n.Link)
+	fmt.Printf("%-16s%sImageNode: '%s' : '%s'\n", formatSpan(n.Span()), spaces(indent), n.LinkText, n.Link)
 }
 
 func (n InlineCodeBlockNode) Print(indent int) {
-	fmt.Printf("%sInlineCodeBlockNode: '%s'\n", spaces(indent), n.Content)
+	fmt.Printf("%-16s%sInlineCodeBlockNode (%s): '%s'\n", formatSpan(n.Span()), spaces(indent), n.Info, n.Content)
 }
 
 func (n InlineCodeNode) Print(indent int) {
-	fmt.Printf("%sInlineCodeNode: '%s'\n", spaces(indent), n.Content)
+	fmt.Printf("%-16s%sInlineCodeNode: '%s'\n", formatSpan(n.Span()), spaces(indent), n.Content)
+}
+
+func (n HtmlBlockNode) Print(indent int) {
+	fmt.Printf("%-16s%sHtmlBlockNode: '%s'\n", formatSpan(n.Span()), spaces(indent), n.Content)
+}
+
+func (n HtmlInlineNode) Print(indent int) {
+	fmt.Printf("%-16s%sHtmlInlineNode: '%s'\n", formatSpan(n.Span()), spaces(indent), n.Content)
 }
 
 func (n BlockQuoteNode) Print(indent int) {
-	fmt.Printf("%sBlockQuoteNode: \n", spaces(indent))
+	fmt.Printf("%-16s%sBlockQuoteNode: \n", formatSpan(n.Span()), spaces(indent))
+	for _, child := range n.Nodes {
+		printNode(child, indent+2)
+	}
+}
+
+func (n TableNode) Print(indent int) {
+	fmt.Printf("%-16s%sTableNode: \n", formatSpan(n.Span()), spaces(indent))
+	for _, child := range n.Nodes {
+		printNode(child, indent+2)
+	}
+}
+
+func (n TableRowNode) Print(indent int) {
+	fmt.Printf("%-16s%sTableRowNode (header %t):\n", formatSpan(n.Span()), spaces(indent), n.IsHeader)
 	for _, child := range n.Nodes {
 		printNode(child, indent+2)
 	}
 }
 
-func printNode(n NodeInterface, indent int) {
+func (n TableCellNode) Print(indent int) {
+	fmt.Printf("%-16s%sTableCellNode (align '%s'):\n", formatSpan(n.Span()), spaces(indent), n.Alignment)
+	for _, child := range n.Content {
+		printNode(child, indent+2)
+	}
+}
+
+func (n FootnoteReferenceNode) Print(indent int) {
+	fmt.Printf("%-16s%sFootnoteReferenceNode: '%s' (%d)\n", formatSpan(n.Span()), spaces(indent), n.Label, n.Index)
+}
+
+func (n FootnoteDefinitionNode) Print(indent int) {
+	fmt.Printf("%-16s%sFootnoteDefinitionNode: '%s' (%d)\n", formatSpan(n.Span()), spaces(indent), n.Label, n.Index)
+	for _, child := range n.Nodes {
+		printNode(child, indent+2)
+	}
+}
+
+func (n DefinitionListNode) Print(indent int) {
+	fmt.Printf("%-16s%sDefinitionListNode: \n", formatSpan(n.Span()), spaces(indent))
+	for _, child := range n.Nodes {
+		printNode(child, indent+2)
+	}
+}
+
+func (n TermNode) Print(indent int) {
+	fmt.Printf("%-16s%sTermNode: \n", formatSpan(n.Span()), spaces(indent))
+	for _, child := range n.Content {
+		printNode(child, indent+2)
+	}
+}
+
+func (n DefinitionNode) Print(indent int) {
+	fmt.Printf("%-16s%sDefinitionNode (tight %t):\n", formatSpan(n.Span()), spaces(indent), n.IsTight)
+	for _, child := range n.Nodes {
+		printNode(child, indent+2)
+	}
+}
+
+func printNode(n Node, indent int) {
 	switch node := n.(type) {
-	case ParagraphNode:
+	case *DocumentNode:
+		node.Print(indent)
+	case *ParagraphNode:
+		node.Print(indent)
+	case *TextNode:
+		node.Print(indent)
+	case *ItalicNode:
+		node.Print(indent)
+	case *BoldNode:
+		node.Print(indent)
+	case *StrikethroughNode:
 		node.Print(indent)
-	case TextNode:
+	case *HeaderNode:
 		node.Print(indent)
-	case ItalicNode:
+	case *NewLineNode:
 		node.Print(indent)
-	case BoldNode:
+	case *LineBreakNode:
 		node.Print(indent)
-	case HeaderNode:
+	case *LinkNode:
 		node.Print(indent)
-	case WhiteSpaceNode:
+	case *ListItemNode:
 		node.Print(indent)
-	case NewLineNode:
+	case *ListNode:
 		node.Print(indent)
-	case NoNode:
+	case *HorizontalRuleNode:
 		node.Print(indent)
-	case LinkNode:
+	case *BlockQuoteNode:
 		node.Print(indent)
-	case ListItemNode:
+	case *ImageNode:
 		node.Print(indent)
-	case ListNode:
+	case *InlineCodeBlockNode:
 		node.Print(indent)
-	case HorizontalRuleNode:
+	case *InlineCodeNode:
 		node.Print(indent)
-	case BlockQuoteNode:
+	case *HtmlBlockNode:
 		node.Print(indent)
-	case ImageNode:
+	case *HtmlInlineNode:
 		node.Print(indent)
-	case InlineCodeBlockNode:
+	case *TableNode:
 		node.Print(indent)
-	case InlineCodeNode:
+	case *TableRowNode:
+		node.Print(indent)
+	case *TableCellNode:
+		node.Print(indent)
+	case *FootnoteReferenceNode:
+		node.Print(indent)
+	case *FootnoteDefinitionNode:
+		node.Print(indent)
+	case *DefinitionListNode:
+		node.Print(indent)
+	case *TermNode:
+		node.Print(indent)
+	case *DefinitionNode:
 		node.Print(indent)
 	default:
-		fmt.Printf("%sUnknown node type\n", spaces(indent))
+		fmt.Printf("%-16s%sUnknown node type %s\n", formatSpan(n.Span()), spaces(indent), n.Kind())
 	}
 }
 
-func PrintNodes(nodes []NodeInterface) {
+func PrintNodes(nodes []Node) {
 	for _, node := range nodes {
 		printNode(node, 0)
 	}
 }
 
+// formatSpan writes a span as "line:column-line:column".
+func formatSpan(span Span) string {
+	return fmt.Sprintf("%d:%d-%d:%d", span.Start.Line, span.Start.Column, span.End.Line, span.End.Column)
+}
+
 func spaces(n int) string {
 	return fmt.Sprintf("%*s", n, "")
 }
@@ -0,0 +1,199 @@
+package parse_test
+
+import (
+	"regexp"
+	"testing"
+)
+
+// specExamples are taken from the CommonMark spec, with the expected HTML as
+// the spec gives it. The void elements the renderer writes are compared
+// without their closing slash.
+var specExamples = []struct {
+	section  string
+	markdown string
+	html     string
+}{
+	{"Tabs", "\tfoo\tbaz\t\tbim\n", "<pre><code>foo\tbaz\t\tbim\n</code></pre>\n"},
+	{"Tabs", "  \tfoo\tbaz\t\tbim\n", "<pre><code>foo\tbaz\t\tbim\n</code></pre>\n"},
+	{"Tabs", "  - foo\n\n\tbar\n", "<ul>\n<li>\n<p>foo</p>\n<p>bar</p>\n</li>\n</ul>\n"},
+	{"Tabs", "- foo\n\n\t\tbar\n", "<ul>\n<li>\n<p>foo</p>\n<pre><code>  bar\n</code></pre>\n</li>\n</ul>\n"},
+	{"Tabs", ">\t\tfoo\n", "<blockquote>\n<pre><code>  foo\n</code></pre>\n</blockquote>\n"},
+
+	{"Backslash escapes", "\\*not emphasized*\n\\<br/> not a tag\n\\[not a link](/foo)\n", "<p>*not emphasized*\n&lt;br/&gt; not a tag\n[not a link](/foo)</p>\n"},
+	{"Backslash escapes", "\\\\*emphasis*\n", "<p>\\<em>emphasis</em></p>\n"},
+	{"Backslash escapes", "foo\\\nbar\n", "<p>foo<br />\nbar</p>\n"},
+	{"Backslash escapes", "`` \\[\\` ``\n", "<p><code>\\[\\`</code></p>\n"},
+
+	{"Entity and numeric character references", "&nbsp; &amp; &copy; &AElig; &Dcaron;\n", "<p>  &amp; © Æ Ď</p>\n"},
+	{"Entity and numeric character references", "&#35; &#1234; &#992; &#0;\n", "<p># Ӓ Ϡ �</p>\n"},
+	{"Entity and numeric character references", "&nbsp &x; &#; &#x;\n", "<p>&amp;nbsp &amp;x; &amp;#; &amp;#x;</p>\n"},
+
+	{"Thematic breaks", "***\n---\n___\n", "<hr />\n<hr />\n<hr />\n"},
+	{"Thematic breaks", "+++\n", "<p>+++</p>\n"},
+	{"Thematic breaks", " - - -\n", "<hr />\n"},
+	{"Thematic breaks", "_____________________________________\n", "<hr />\n"},
+	{"Thematic breaks", "Foo\n***\nbar\n", "<p>Foo</p>\n<hr />\n<p>bar</p>\n"},
+	{"Thematic breaks", "Foo\n---\nbar\n", "<h2>Foo</h2>\n<p>bar</p>\n"},
+	{"Thematic breaks", "* Foo\n* * *\n* Bar\n", "<ul>\n<li>Foo</li>\n</ul>\n<hr />\n<ul>\n<li>Bar</li>\n</ul>\n"},
+	{"Thematic breaks", "- Foo\n- * * *\n", "<ul>\n<li>Foo</li>\n<li>\n<hr />\n</li>\n</ul>\n"},
+
+	{"ATX headings", "# foo\n## foo\n### foo\n#### foo\n##### foo\n###### foo\n", "<h1>foo</h1>\n<h2>foo</h2>\n<h3>foo</h3>\n<h4>foo</h4>\n<h5>foo</h5>\n<h6>foo</h6>\n"},
+	{"ATX headings", "####### foo\n", "<p>####### foo</p>\n"},
+	{"ATX headings", "#5 bolt\n\n#hashtag\n", "<p>#5 bolt</p>\n<p>#hashtag</p>\n"},
+	{"ATX headings", "# foo *bar* \\*baz\\*\n", "<h1>foo <em>bar</em> *baz*</h1>\n"},
+	{"ATX headings", "## foo ##\n  ###   bar    ###\n", "<h2>foo</h2>\n<h3>bar</h3>\n"},
+	{"ATX headings", "### foo ### b\n", "<h3>foo ### b</h3>\n"},
+	{"ATX headings", "# foo#\n", "<h1>foo#</h1>\n"},
+	{"ATX headings", "## \n#\n### ###\n", "<h2></h2>\n<h1></h1>\n<h3></h3>\n"},
+
+	{"Setext headings", "Foo *bar*\n=========\n\nFoo *bar*\n---------\n", "<h1>Foo <em>bar</em></h1>\n<h2>Foo <em>bar</em></h2>\n"},
+	{"Setext headings", "Foo *bar\nbaz*\n====\n", "<h1>Foo <em>bar\nbaz</em></h1>\n"},
+	{"Setext headings", "Foo\n= =\n\nFoo\n--- -\n", "<p>Foo\n= =</p>\n<p>Foo</p>\n<hr />\n"},
+	{"Setext headings", "> foo\nbar\n===\n", "<blockquote>\n<p>foo\nbar\n===</p>\n</blockquote>\n"},
+	{"Setext headings", "- Foo\n---\n", "<ul>\n<li>Foo</li>\n</ul>\n<hr />\n"},
+	{"Setext headings", "\n====\n", "<p>====</p>\n"},
+	{"Setext headings", "Foo\nbar\n---\nbaz\n", "<h2>Foo\nbar</h2>\n<p>baz</p>\n"},
+
+	{"Indented code blocks", "    a simple\n      indented code block\n", "<pre><code>a simple\n  indented code block\n</code></pre>\n"},
+	{"Indented code blocks", "  - foo\n\n    bar\n", "<ul>\n<li>\n<p>foo</p>\n<p>bar</p>\n</li>\n</ul>\n"},
+	{"Indented code blocks", "    chunk1\n\n    chunk2\n  \n \n \n    chunk3\n", "<pre><code>chunk1\n\nchunk2\n\n\n\nchunk3\n</code></pre>\n"},
+	{"Indented code blocks", "Foo\n    bar\n", "<p>Foo\nbar</p>\n"},
+	{"Indented code blocks", "        foo\n    bar\n", "<pre><code>    foo\nbar\n</code></pre>\n"},
+
+	{"Fenced code blocks", "```\n<\n >\n```\n", "<pre><code>&lt;\n &gt;\n</code></pre>\n"},
+	{"Fenced code blocks", "``\nfoo\n``\n", "<p><code>foo</code></p>\n"},
+	{"Fenced code blocks", "```\naaa\n~~~\n```\n", "<pre><code>aaa\n~~~\n</code></pre>\n"},
+	{"Fenced code blocks", "````\naaa\n```\n``````\n", "<pre><code>aaa\n```\n</code></pre>\n"},
+	{"Fenced code blocks", "```\n", "<pre><code></code></pre>\n"},
+	{"Fenced code blocks", "> ```\n> aaa\n\nbbb\n", "<blockquote>\n<pre><code>aaa\n</code></pre>\n</blockquote>\n<p>bbb</p>\n"},
+	{"Fenced code blocks", " ```\n aaa\naaa\n```\n", "<pre><code>aaa\naaa\n</code></pre>\n"},
+	{"Fenced code blocks", "```ruby\ndef foo(x)\n  return 3\nend\n```\n", "<pre><code class=\"language-ruby\">def foo(x)\n  return 3\nend\n</code></pre>\n"},
+	{"Fenced code blocks", "``` aa ```\nfoo\n", "<p><code>aa</code>\nfoo</p>\n"},
+
+	{"HTML blocks", "<table><tr><td>\n<pre>\n**Hello**,\n\n_world_.\n</pre>\n</td></tr></table>\n", "<table><tr><td>\n<pre>\n**Hello**,\n<p><em>world</em>.\n</pre></p>\n</td></tr></table>\n"},
+	{"HTML blocks", "<div>\n*hello*\n         <foo><a>\n", "<div>\n*hello*\n         <foo><a>\n"},
+	{"HTML blocks", "<!-- foo -->*bar*\n*baz*\n", "<!-- foo -->*bar*\n<p><em>baz</em></p>\n"},
+	{"HTML blocks", "Foo\n<div>\nbar\n</div>\n", "<p>Foo</p>\n<div>\nbar\n</div>\n"},
+	{"HTML blocks", "Foo\n<a href=\"bar\">\nbaz\n", "<p>Foo\n<a href=\"bar\">\nbaz</p>\n"},
+
+	{"Link reference definitions", "[foo]: /url \"title\"\n\n[foo]\n", "<p><a href=\"/url\" title=\"title\">foo</a></p>\n"},
+	{"Link reference definitions", "[Foo bar]:\n<my url>\n'title'\n\n[Foo bar]\n", "<p><a href=\"my%20url\" title=\"title\">Foo bar</a></p>\n"},
+	{"Link reference definitions", "[FOO]: /url\n\n[Foo]\n", "<p><a href=\"/url\">Foo</a></p>\n"},
+	{"Link reference definitions", "[foo]: /url \"title\" ok\n", "<p>[foo]: /url &quot;title&quot; ok</p>\n"},
+	{"Link reference definitions", "[foo]\n\n[foo]: first\n[foo]: second\n", "<p><a href=\"first\">foo</a></p>\n"},
+	{"Link reference definitions", "[foo]: /url\nbar\n===\n[foo]\n", "<h1>bar</h1>\n<p><a href=\"/url\">foo</a></p>\n"},
+
+	{"Paragraphs", "aaa\n\nbbb\n", "<p>aaa</p>\n<p>bbb</p>\n"},
+	{"Paragraphs", "  aaa\n bbb\n", "<p>aaa\nbbb</p>\n"},
+	{"Paragraphs", "aaa     \nbbb     \n", "<p>aaa<br />\nbbb</p>\n"},
+
+	{"Block quotes", "> # Foo\n> bar\n> baz\n", "<blockquote>\n<h1>Foo</h1>\n<p>bar\nbaz</p>\n</blockquote>\n"},
+	{"Block quotes", "> bar\nbaz\n> foo\n", "<blockquote>\n<p>bar\nbaz\nfoo</p>\n</blockquote>\n"},
+	{"Block quotes", "> - foo\n- bar\n", "<blockquote>\n<ul>\n<li>foo</li>\n</ul>\n</blockquote>\n<ul>\n<li>bar</li>\n</ul>\n"},
+	{"Block quotes", ">     foo\n    bar\n", "<blockquote>\n<pre><code>foo\n</code></pre>\n</blockquote>\n<pre><code>bar\n</code></pre>\n"},
+	{"Block quotes", ">\n", "<blockquote>\n</blockquote>\n"},
+	{"Block quotes", "> foo\n\n> bar\n", "<blockquote>\n<p>foo</p>\n</blockquote>\n<blockquote>\n<p>bar</p>\n</blockquote>\n"},
+	{"Block quotes", "> > > foo\nbar\n", "<blockquote>\n<blockquote>\n<blockquote>\n<p>foo\nbar</p>\n</blockquote>\n</blockquote>\n</blockquote>\n"},
+
+	{"List items", "1.  A paragraph\n    with two lines.\n\n        indented code\n\n    > A block quote.\n", "<ol>\n<li>\n<p>A paragraph\nwith two lines.</p>\n<pre><code>indented code\n</code></pre>\n<blockquote>\n<p>A block quote.</p>\n</blockquote>\n</li>\n</ol>\n"},
+	{"List items", "- one\n\n two\n", "<ul>\n<li>one</li>\n</ul>\n<p>two</p>\n"},
+	{"List items", "- one\n\n  two\n", "<ul>\n<li>\n<p>one</p>\n<p>two</p>\n</li>\n</ul>\n"},
+	{"List items", "-one\n\n2.two\n", "<p>-one</p>\n<p>2.two</p>\n"},
+	{"List items", "1234567890. not ok\n", "<p>1234567890. not ok</p>\n"},
+	{"List items", "003. ok\n", "<ol start=\"3\">\n<li>ok</li>\n</ol>\n"},
+	{"List items", "-1. not ok\n", "<p>-1. not ok</p>\n"},
+	{"List items", "-\n  foo\n-\n  ```\n  bar\n  ```\n-\n      baz\n", "<ul>\n<li>foo</li>\n<li>\n<pre><code>bar\n</code></pre>\n</li>\n<li>\n<pre><code>baz\n</code></pre>\n</li>\n</ul>\n"},
+	{"List items", "-\n\n  foo\n", "<ul>\n<li></li>\n</ul>\n<p>foo</p>\n"},
+	{"List items", "foo\n*\n\nfoo\n1.\n", "<p>foo\n*</p>\n<p>foo\n1.</p>\n"},
+	{"List items", "- foo\n  - bar\n    - baz\n      - boo\n", "<ul>\n<li>foo\n<ul>\n<li>bar\n<ul>\n<li>baz\n<ul>\n<li>boo</li>\n</ul>\n</li>\n</ul>\n</li>\n</ul>\n</li>\n</ul>\n"},
+	{"List items", "- # Foo\n- Bar\n  ---\n  baz\n", "<ul>\n<li>\n<h1>Foo</h1>\n</li>\n<li>\n<h2>Bar</h2>\nbaz</li>\n</ul>\n"},
+
+	{"Lists", "- foo\n- bar\n+ baz\n", "<ul>\n<li>foo</li>\n<li>bar</li>\n</ul>\n<ul>\n<li>baz</li>\n</ul>\n"},
+	{"Lists", "The number of windows in my house is\n14.  The number of doors is 6.\n", "<p>The number of windows in my house is\n14.  The number of doors is 6.</p>\n"},
+	{"Lists", "- foo\n\n- bar\n\n\n- baz\n", "<ul>\n<li>\n<p>foo</p>\n</li>\n<li>\n<p>bar</p>\n</li>\n<li>\n<p>baz</p>\n</li>\n</ul>\n"},
+	{"Lists", "- foo\n- bar\n\n<!-- -->\n\n- baz\n- bim\n", "<ul>\n<li>foo</li>\n<li>bar</li>\n</ul>\n<!-- -->\n<ul>\n<li>baz</li>\n<li>bim</li>\n</ul>\n"},
+	{"Lists", "- a\n- b\n\n- c\n", "<ul>\n<li>\n<p>a</p>\n</li>\n<li>\n<p>b</p>\n</li>\n<li>\n<p>c</p>\n</li>\n</ul>\n"},
+	{"Lists", "- a\n- ```\n  b\n\n\n  ```\n- c\n", "<ul>\n<li>a</li>\n<li>\n<pre><code>b\n\n\n</code></pre>\n</li>\n<li>c</li>\n</ul>\n"},
+	{"Lists", "- a\n  - b\n\n    c\n- d\n", "<ul>\n<li>a\n<ul>\n<li>\n<p>b</p>\n<p>c</p>\n</li>\n</ul>\n</li>\n<li>d</li>\n</ul>\n"},
+	{"Lists", "* a\n> b\n>\n* c\n", "<ul>\n<li>a</li>\n</ul>\n<blockquote>\n<p>b</p>\n</blockquote>\n<ul>\n<li>c</li>\n</ul>\n"},
+	{"Lists", "- a\n  > b\n  ```\n  c\n  ```\n- d\n", "<ul>\n<li>a\n<blockquote>\n<p>b</p>\n</blockquote>\n<pre><code>c\n</code></pre>\n</li>\n<li>d</li>\n</ul>\n"},
+	{"Lists", "1. ```\n   foo\n   ```\n\n   bar\n", "<ol>\n<li>\n<pre><code>foo\n</code></pre>\n<p>bar</p>\n</li>\n</ol>\n"},
+	{"Lists", "* foo\n  * bar\n\n  baz\n", "<ul>\n<li>\n<p>foo</p>\n<ul>\n<li>bar</li>\n</ul>\n<p>baz</p>\n</li>\n</ul>\n"},
+	{"Lists", "- a\n  - b\n  - c\n\n- d\n  - e\n  - f\n", "<ul>\n<li>\n<p>a</p>\n<ul>\n<li>b</li>\n<li>c</li>\n</ul>\n</li>\n<li>\n<p>d</p>\n<ul>\n<li>e</li>\n<li>f</li>\n</ul>\n</li>\n</ul>\n"},
+
+	{"Code spans", "`foo`\n", "<p><code>foo</code></p>\n"},
+	{"Code spans", "`` foo ` bar ``\n", "<p><code>foo ` bar</code></p>\n"},
+	{"Code spans", "` `` `\n", "<p><code>``</code></p>\n"},
+	{"Code spans", "`  ``  `\n", "<p><code> `` </code></p>\n"},
+	{"Code spans", "``\nfoo\nbar  \nbaz\n``\n", "<p><code>foo bar   baz</code></p>\n"},
+	{"Code spans", "`foo\\`bar`\n", "<p><code>foo\\</code>bar`</p>\n"},
+	{"Code spans", "*foo`*`\n", "<p>*foo<code>*</code></p>\n"},
+	{"Code spans", "`<a href=\"`\">`\n", "<p><code>&lt;a href=&quot;</code>&quot;&gt;`</p>\n"},
+	{"Code spans", "```foo``\n", "<p>```foo``</p>\n"},
+
+	{"Emphasis and strong emphasis", "*foo bar*\n", "<p><em>foo bar</em></p>\n"},
+	{"Emphasis and strong emphasis", "a * foo bar*\n", "<p>a * foo bar*</p>\n"},
+	{"Emphasis and strong emphasis", "foo*bar*\n", "<p>foo<em>bar</em></p>\n"},
+	{"Emphasis and strong emphasis", "_foo_bar\n", "<p>_foo_bar</p>\n"},
+	{"Emphasis and strong emphasis", "foo-_(bar)_\n", "<p>foo-<em>(bar)</em></p>\n"},
+	{"Emphasis and strong emphasis", "**foo bar**\n", "<p><strong>foo bar</strong></p>\n"},
+	{"Emphasis and strong emphasis", "__foo__bar\n", "<p>__foo__bar</p>\n"},
+	{"Emphasis and strong emphasis", "*foo**bar**baz*\n", "<p><em>foo<strong>bar</strong>baz</em></p>\n"},
+	{"Emphasis and strong emphasis", "foo***bar***baz\n", "<p>foo<em><strong>bar</strong></em>baz</p>\n"},
+	{"Emphasis and strong emphasis", "foo******bar*********baz\n", "<p>foo<strong><strong><strong>bar</strong></strong></strong>***baz</p>\n"},
+	{"Emphasis and strong emphasis", "*foo *bar**\n", "<p><em>foo <em>bar</em></em></p>\n"},
+	{"Emphasis and strong emphasis", "**foo*\n", "<p>*<em>foo</em></p>\n"},
+	{"Emphasis and strong emphasis", "*foo**\n", "<p><em>foo</em>*</p>\n"},
+	{"Emphasis and strong emphasis", "***foo***\n", "<p><em><strong>foo</strong></em></p>\n"},
+	{"Emphasis and strong emphasis", "*[bar*](/url)\n", "<p>*<a href=\"/url\">bar*</a></p>\n"},
+	{"Emphasis and strong emphasis", "**a<http://foo.bar/?q=**>\n", "<p>**a<a href=\"http://foo.bar/?q=**\">http://foo.bar/?q=**</a></p>\n"},
+
+	{"Links", "[link](/uri \"title\")\n", "<p><a href=\"/uri\" title=\"title\">link</a></p>\n"},
+	{"Links", "[link]()\n", "<p><a href=\"\">link</a></p>\n"},
+	{"Links", "[link](</my uri>)\n", "<p><a href=\"/my%20uri\">link</a></p>\n"},
+	{"Links", "[link](foo(and(bar)))\n", "<p><a href=\"foo(and(bar))\">link</a></p>\n"},
+	{"Links", "[link](foo\\)\\:)\n", "<p><a href=\"foo):\">link</a></p>\n"},
+	{"Links", "[link](\"title\")\n", "<p><a href=\"%22title%22\">link</a></p>\n"},
+	{"Links", "[link [foo [bar]]](/uri)\n", "<p><a href=\"/uri\">link [foo [bar]]</a></p>\n"},
+	{"Links", "[link *foo **bar** `#`*](/uri)\n", "<p><a href=\"/uri\">link <em>foo <strong>bar</strong> <code>#</code></em></a></p>\n"},
+	{"Links", "[![moon](moon.jpg)](/uri)\n", "<p><a href=\"/uri\"><img src=\"moon.jpg\" alt=\"moon\" /></a></p>\n"},
+	{"Links", "[foo [bar](/uri)](/uri)\n", "<p>[foo <a href=\"/uri\">bar</a>](/uri)</p>\n"},
+	{"Links", "[foo`](/uri)`\n", "<p>[foo<code>](/uri)</code></p>\n"},
+	{"Links", "[foo][bar]\n\n[bar]: /url \"title\"\n", "<p><a href=\"/url\" title=\"title\">foo</a></p>\n"},
+	{"Links", "[Foo][]\n\n[foo]: /url \"title\"\n", "<p><a href=\"/url\" title=\"title\">Foo</a></p>\n"},
+	{"Links", "[foo] bar\n\n[foo]: /url\n", "<p><a href=\"/url\">foo</a> bar</p>\n"},
+	{"Links", "[foo][bar][baz]\n\n[baz]: /url\n", "<p>[foo]<a href=\"/url\">bar</a></p>\n"},
+
+	{"Images", "![foo *bar*]\n\n[foo *bar*]: train.jpg \"train & tracks\"\n", "<p><img src=\"train.jpg\" alt=\"foo bar\" title=\"train &amp; tracks\" /></p>\n"},
+	{"Images", "![foo ![bar](/url)](/url2)\n", "<p><img src=\"/url2\" alt=\"foo bar\" /></p>\n"},
+	{"Images", "![](/url)\n", "<p><img src=\"/url\" alt=\"\" /></p>\n"},
+
+	{"Autolinks", "<http://foo.bar.baz>\n", "<p><a href=\"http://foo.bar.baz\">http://foo.bar.baz</a></p>\n"},
+	{"Autolinks", "<foo@bar.example.com>\n", "<p><a href=\"mailto:foo@bar.example.com\">foo@bar.example.com</a></p>\n"},
+	{"Autolinks", "<http://foo.bar/baz bim>\n", "<p>&lt;http://foo.bar/baz bim&gt;</p>\n"},
+	{"Autolinks", "http://example.com\n", "<p>http://example.com</p>\n"},
+
+	{"Raw HTML", "<a><bab><c2c>\n", "<p><a><bab><c2c></p>\n"},
+	{"Raw HTML", "<a  /><b2\ndata=\"foo\" >\n", "<p><a  /><b2\ndata=\"foo\" ></p>\n"},
+	{"Raw HTML", "<33> <__>\n", "<p>&lt;33&gt; &lt;__&gt;</p>\n"},
+	{"Raw HTML", "foo <!-- this is a --\ncomment - with hyphens -->\n", "<p>foo <!-- this is a --\ncomment - with hyphens --></p>\n"},
+
+	{"Hard line breaks", "foo  \nbaz\n", "<p>foo<br />\nbaz</p>\n"},
+	{"Hard line breaks", "*foo  \nbar*\n", "<p><em>foo<br />\nbar</em></p>\n"},
+	{"Hard line breaks", "`code  \nspan`\n", "<p><code>code   span</code></p>\n"},
+	{"Hard line breaks", "foo\\\n", "<p>foo\\</p>\n"},
+	{"Hard line breaks", "### foo  \n", "<h3>foo</h3>\n"},
+
+	{"Soft line breaks", "foo \n baz\n", "<p>foo\nbaz</p>\n"},
+}
+
+var voidElement = regexp.MustCompile(`<(hr|br|img [^>]*) />`)
+
+func TestSpecExamples(t *testing.T) {
+	for _, example := range specExamples {
+		html := voidElement.ReplaceAllString(example.html, "<$1>")
+		if got := render(example.markdown, 0); got != html {
+			t.Errorf("%s: %q\ngot:\n%s\nwant:\n%s", example.section, example.markdown, got, html)
+		}
+	}
+}
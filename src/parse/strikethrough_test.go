@@ -0,0 +1,27 @@
+package parse_test
+
+import (
+	"allium/src/parse"
+	"testing"
+)
+
+func TestStrikethrough(t *testing.T) {
+	tests := map[string]string{
+		"~~a~~ ~b~\n":           "<p><del>a</del> <del>b</del></p>\n",
+		"a ~~b~ ~~~c~~~ d~~\n":  "<p>a <del>b~ ~~~c~~~ d</del></p>\n",
+		"*~~a~~ b*\n":           "<p><em><del>a</del> b</em></p>\n",
+		"~~a *b~~ c*\n":         "<p><del>a *b</del> c*</p>\n",
+		"\\~~a~~\n":             "<p>~~a~~</p>\n",
+		"`~~code~~` ~~text~~\n": "<p><code>~~code~~</code> <del>text</del></p>\n",
+	}
+
+	for markdown, want := range tests {
+		if html := render(markdown, parse.StrikethroughExtension); html != want {
+			t.Errorf("%q: got %q, want %q", markdown, html, want)
+		}
+	}
+
+	if html := render("~~a~~\n", 0); html != "<p>~~a~~</p>\n" {
+		t.Errorf("without the extension: got %q", html)
+	}
+}
@@ -0,0 +1,181 @@
+package parse
+
+import (
+	"regexp"
+	"strings"
+)
+
+type tableData struct {
+	headerRow  string
+	headerLine sourceLine
+	alignments []string
+}
+
+var tableDelimiterRow = regexp.MustCompile(`^\|?[ \t]*:?-+:?[ \t]*(?:\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+
+// startTable turns the last line of an open paragraph into a table header
+// when the current line is a matching delimiter row.
+func startTable(p *Parser, container *block) int {
+	if !p.hasExtension(TablesExtension) || p.indented || container.kind != paragraphBlock {
+		return startNone
+	}
+
+	line := p.currentLine[p.nextNonspace:]
+	if !strings.Contains(line, "|") || !tableDelimiterRow.MatchString(line) {
+		return startNone
+	}
+
+	content := strings.TrimSuffix(container.content.String(), "\n")
+	before, headerRow := "", content
+	if i := strings.LastIndex(content, "\n"); i >= 0 {
+		before, headerRow = content[:i+1], content[i+1:]
+	}
+
+	header := splitTableRow(headerRow)
+	alignments := parseTableAlignments(line)
+	if len(header) != len(alignments) {
+		return startNone
+	}
+
+	p.closeUnmatchedBlocks()
+
+	// the header row is the last line added to the paragraph
+	headerLine := container.source.lines[len(container.source.lines)-1]
+
+	// lines above the header row stay behind as a paragraph of their own
+	startLine := container.startLine
+	if before != "" {
+		container.content.Reset()
+		container.content.WriteString(before)
+		p.finalize(container, p.lineNumber-2)
+		startLine = p.lineNumber - 1
+	} else {
+		container.parent.removeChild(container)
+		p.tip = container.parent
+	}
+
+	table := p.addChild(tableBlock, container.startColumn-1)
+	table.startLine = startLine
+	table.table = &tableData{headerRow: headerRow, headerLine: headerLine, alignments: alignments}
+	p.advanceOffset(len(p.currentLine)-p.offset, false)
+
+	return startLeaf
+}
+
+func parseTableAlignments(row string) []string {
+	var alignments []string
+
+	for _, cell := range splitTableRow(row) {
+		left := strings.HasPrefix(cell, ":")
+		right := strings.HasSuffix(cell, ":")
+
+		switch {
+		case left && right:
+			alignments = append(alignments, "center")
+		case left:
+			alignments = append(alignments, "left")
+		case right:
+			alignments = append(alignments, "right")
+		default:
+			alignments = append(alignments, "")
+		}
+	}
+
+	return alignments
+}
+
+// splitTableRow splits a row on its unescaped pipes, dropping the optional
+// leading and trailing pipe. Escaped pipes are kept as plain pipes in the
+// cell so they survive inline parsing, even inside code spans.
+func splitTableRow(row string) []string {
+	cells, _ := splitTableRowOffsets(row)
+	return cells
+}
+
+// splitTableRowOffsets also returns the offset in the row at which each
+// cell's content starts.
+func splitTableRowOffsets(row string) ([]string, []int) {
+	end := len(strings.TrimRight(row, " \t"))
+	start := len(row) - len(strings.TrimLeft(row, " \t"))
+	if start > end {
+		start = end
+	}
+	if strings.HasPrefix(row[start:end], "|") {
+		start++
+	}
+	if strings.HasSuffix(row[start:end], "|") && !strings.HasSuffix(row[start:end], "\\|") {
+		end--
+	}
+
+	var cells []string
+	var offsets []int
+	var cell strings.Builder
+	cellStart := start
+	finish := func(i int) {
+		content := cell.String()
+		cells = append(cells, strings.TrimSpace(content))
+		offsets = append(offsets, cellStart+len(content)-len(strings.TrimLeft(content, " \t")))
+		cell.Reset()
+		cellStart = i + 1
+	}
+
+	for i := start; i < end; i++ {
+		switch {
+		case row[i] == '\\' && peek(row, i+1) == '|':
+			cell.WriteByte('|')
+			i++
+		case row[i] == '|':
+			finish(i)
+		default:
+			cell.WriteByte(row[i])
+		}
+	}
+	finish(end)
+
+	return cells, offsets
+}
+
+func (p *Parser) buildTable(b *block) Node {
+	var node TableNode
+	node.Nodes = append(node.Nodes, p.buildTableRow(b.table.headerRow, b.table.headerLine, b.table.alignments, true))
+
+	for i, line := range strings.Split(b.content.String(), "\n") {
+		if isBlank(line) {
+			continue
+		}
+		node.Nodes = append(node.Nodes, p.buildTableRow(line, b.source.lines[i], b.table.alignments, false))
+	}
+
+	return &node
+}
+
+// buildTableRow makes a row with exactly one cell per column, dropping
+// excess cells and filling in missing ones.
+func (p *Parser) buildTableRow(line string, source sourceLine, alignments []string, isHeader bool) Node {
+	var row TableRowNode
+	row.IsHeader = isHeader
+	row.span = Span{
+		Start: Position{Line: source.line, Column: source.column},
+		End:   Position{Line: source.line, Column: source.column + len(line) - 1},
+	}
+
+	cells, offsets := splitTableRowOffsets(line)
+	for i, alignment := range alignments {
+		var cell TableCellNode
+		cell.Alignment = alignment
+		cell.IsHeader = isHeader
+		if i < len(cells) {
+			var cellSource sourceMap
+			cellSource.lines = []sourceLine{{line: source.line, column: source.column + offsets[i]}}
+
+			cell.Content = p.parseInlines(cells[i], cellSource)
+			cell.span = cellSource.span(0, len(cells[i]))
+		} else {
+			// a missing cell takes the place at the end of the row
+			cell.span = Span{Start: row.span.End, End: row.span.End}
+		}
+		row.Nodes = append(row.Nodes, &cell)
+	}
+
+	return &row
+}
@@ -0,0 +1,47 @@
+package parse_test
+
+import (
+	"allium/src/parse"
+	"testing"
+)
+
+func TestTables(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		html     string
+	}{
+		{
+			name:     "alignment, escaped pipes, inlines and missing cells",
+			markdown: "| a | b | c |\n|:--|:-:|--:|\n| 1 | `x\\|y` | *e* |\n| 2 |\n",
+			html: "<table>\n<thead>\n<tr>\n<th align=\"left\">a</th>\n<th align=\"center\">b</th>\n<th align=\"right\">c</th>\n</tr>\n</thead>\n" +
+				"<tbody>\n<tr>\n<td align=\"left\">1</td>\n<td align=\"center\"><code>x|y</code></td>\n<td align=\"right\"><em>e</em></td>\n</tr>\n" +
+				"<tr>\n<td align=\"left\">2</td>\n<td align=\"center\"></td>\n<td align=\"right\"></td>\n</tr>\n</tbody>\n</table>\n",
+		},
+		{
+			name:     "header only, without outer pipes",
+			markdown: "a | b\n--|--\n",
+			html:     "<table>\n<thead>\n<tr>\n<th>a</th>\n<th>b</th>\n</tr>\n</thead>\n</table>\n",
+		},
+		{
+			name:     "ended by a blank line",
+			markdown: "| a |\n| - |\n| b |\n\nc\n",
+			html:     "<table>\n<thead>\n<tr>\n<th>a</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>b</td>\n</tr>\n</tbody>\n</table>\n<p>c</p>\n",
+		},
+		{
+			name:     "delimiter row with a different number of cells",
+			markdown: "| a | b |\n| - |\n",
+			html:     "<p>| a | b |\n| - |</p>\n",
+		},
+	}
+
+	for _, test := range tests {
+		if html := render(test.markdown, parse.TablesExtension); html != test.html {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.name, html, test.html)
+		}
+	}
+
+	if html := render("| a |\n| - |\n", 0); html != "<p>| a |\n| - |</p>\n" {
+		t.Errorf("without the extension: got\n%s", html)
+	}
+}
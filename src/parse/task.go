@@ -0,0 +1,29 @@
+package parse
+
+import "regexp"
+
+var taskMarker = regexp.MustCompile(`^\[([ xX])\](?:[ \t]+|$)`)
+
+// parseTaskMarker strips a leading "[ ]" or "[x]" from the first paragraph
+// of a list item and reports whether it was checked, or nil when the item
+// is not a task.
+func parseTaskMarker(item *block) *bool {
+	if len(item.children) == 0 || item.children[0].kind != paragraphBlock {
+		return nil
+	}
+
+	paragraph := item.children[0]
+	content := paragraph.content.String()
+
+	match := taskMarker.FindStringSubmatch(content)
+	if match == nil || len(match[0]) == len(content) {
+		return nil
+	}
+
+	paragraph.content.Reset()
+	paragraph.content.WriteString(content[len(match[0]):])
+	paragraph.source.trimmed += len(match[0])
+
+	checked := match[1] != " "
+	return &checked
+}
@@ -0,0 +1,35 @@
+package parse_test
+
+import (
+	"allium/src/parse"
+	"testing"
+)
+
+func TestTaskListItems(t *testing.T) {
+	document := parseMarkdown("- [x] done\n- [X] also done\n- [ ] todo\n- [y] not a task\n- [ ]\n- plain\n", parse.TaskListExtension)
+	list := document.Nodes[0].(*parse.ListNode)
+
+	want := []string{"true", "true", "false", "nil", "nil", "nil"}
+	for i, node := range list.Nodes {
+		got := "nil"
+		if checked := node.(*parse.ListItemNode).Checked; checked != nil {
+			got = map[bool]string{true: "true", false: "false"}[*checked]
+		}
+		if got != want[i] {
+			t.Errorf("item %d: checked is %s, want %s", i+1, got, want[i])
+		}
+	}
+
+	html := render("1. [x] a\n2. [ ] b\n", parse.TaskListExtension)
+	wantHtml := "<ol class=\"contains-task-list\">\n" +
+		"<li class=\"task-list-item\"><input type=\"checkbox\" class=\"task-list-item-checkbox\" disabled checked> a</li>\n" +
+		"<li class=\"task-list-item\"><input type=\"checkbox\" class=\"task-list-item-checkbox\" disabled> b</li>\n" +
+		"</ol>\n"
+	if html != wantHtml {
+		t.Errorf("got\n%s\nwant\n%s", html, wantHtml)
+	}
+
+	if item := parseMarkdown("- [x] a\n", 0).Nodes[0].(*parse.ListNode).Nodes[0].(*parse.ListItemNode); item.Checked != nil {
+		t.Errorf("item is a task without the extension")
+	}
+}
@@ -0,0 +1,106 @@
+package parse
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Transformer rewrites a parsed document in place before it is rendered.
+type Transformer func(document *DocumentNode) error
+
+// Transform runs each transformer over the document in order, stopping at
+// the first one to fail. Parent links are brought up to date afterwards.
+func Transform(document *DocumentNode, transformers ...Transformer) error {
+	for _, transform := range transformers {
+		if err := transform(document); err != nil {
+			return err
+		}
+	}
+
+	SetParents(document)
+	return nil
+}
+
+// AbsoluteLinks resolves relative link destinations against base.
+// Fragment only links to the same page are left alone.
+func AbsoluteLinks(base string) Transformer {
+	return func(document *DocumentNode) error {
+		baseUrl, err := url.Parse(base)
+		if err != nil {
+			return fmt.Errorf("invalid base URL %q: %w", base, err)
+		}
+
+		for _, node := range FindAll(document, LinkKind) {
+			link := node.(*LinkNode)
+			link.Link = resolveRelative(baseUrl, link.Link)
+		}
+
+		return nil
+	}
+}
+
+// ImageCDN resolves relative image sources against the URL of a CDN, so
+// "img/logo.png" and "/img/logo.png" both become
+// "https://cdn.example.com/img/logo.png". Sources with a scheme or a host,
+// including protocol relative ones, are left alone.
+func ImageCDN(cdn string) Transformer {
+	return func(document *DocumentNode) error {
+		// without a trailing slash the last path segment would be replaced
+		cdnUrl, err := url.Parse(strings.TrimSuffix(cdn, "/") + "/")
+		if err != nil {
+			return fmt.Errorf("invalid CDN URL %q: %w", cdn, err)
+		}
+
+		for _, node := range FindAll(document, ImageKind) {
+			image := node.(*ImageNode)
+			if image.Link == "" || strings.HasPrefix(image.Link, "#") {
+				continue
+			}
+
+			reference, err := url.Parse(image.Link)
+			if err != nil || reference.IsAbs() || reference.Host != "" {
+				continue
+			}
+
+			// a path absolute source is kept under the CDN path rather
+			// than replacing it
+			reference.Path = strings.TrimPrefix(reference.Path, "/")
+			reference.RawPath = strings.TrimPrefix(reference.RawPath, "/")
+			image.Link = cdnUrl.ResolveReference(reference).String()
+		}
+
+		return nil
+	}
+}
+
+// ShiftHeadings moves every heading down by levels, or up when negative,
+// keeping levels between 1 and 6.
+func ShiftHeadings(levels int) Transformer {
+	return func(document *DocumentNode) error {
+		for _, node := range FindAll(document, HeaderKind) {
+			header := node.(*HeaderNode)
+			header.Level = min(max(header.Level+levels, 1), 6)
+
+			// setext underlines only exist for the first two levels
+			if header.Level > 2 {
+				header.IsSetext = false
+			}
+		}
+
+		return nil
+	}
+}
+
+func resolveRelative(base *url.URL, link string) string {
+	if link == "" || strings.HasPrefix(link, "#") {
+		return link
+	}
+
+	reference, err := url.Parse(link)
+	if err != nil || reference.IsAbs() || reference.Host != "" {
+		return link
+	}
+
+	return base.ResolveReference(reference).String()
+}
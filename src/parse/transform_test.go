@@ -0,0 +1,102 @@
+package parse_test
+
+import (
+	"allium/src/parse"
+	"testing"
+)
+
+func TestImageCDN(t *testing.T) {
+	tests := []struct {
+		name   string
+		cdn    string
+		source string
+		link   string
+	}{
+		{
+			name:   "relative",
+			cdn:    "https://cdn.example.com",
+			source: "img/logo.png",
+			link:   "https://cdn.example.com/img/logo.png",
+		},
+		{
+			name:   "relative under a CDN path",
+			cdn:    "https://cdn.example.com/assets",
+			source: "img/logo.png",
+			link:   "https://cdn.example.com/assets/img/logo.png",
+		},
+		{
+			name:   "path absolute",
+			cdn:    "https://cdn.example.com/assets/",
+			source: "/img/logo.png",
+			link:   "https://cdn.example.com/assets/img/logo.png",
+		},
+		{
+			name:   "path absolute with a doubled slash",
+			cdn:    "https://cdn.example.com/assets",
+			source: "/img//logo.png",
+			link:   "https://cdn.example.com/assets/img//logo.png",
+		},
+		{
+			name:   "colon in the first segment",
+			cdn:    "https://cdn.example.com/assets",
+			source: "/a:b.png",
+			link:   "https://cdn.example.com/assets/a:b.png",
+		},
+		{
+			name:   "query kept",
+			cdn:    "https://cdn.example.com",
+			source: "logo.png?v=2",
+			link:   "https://cdn.example.com/logo.png?v=2",
+		},
+		{
+			name:   "protocol relative",
+			cdn:    "https://cdn.example.com/assets",
+			source: "//other.example/x.png",
+			link:   "//other.example/x.png",
+		},
+		{
+			name:   "absolute",
+			cdn:    "https://cdn.example.com/assets",
+			source: "http://other.example/x.png",
+			link:   "http://other.example/x.png",
+		},
+		{
+			name:   "data URI",
+			cdn:    "https://cdn.example.com/assets",
+			source: "data:image/png;base64,AAAA",
+			link:   "data:image/png;base64,AAAA",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			document := parseMarkdown("![logo]("+test.source+")", 0)
+			if err := parse.Transform(document, parse.ImageCDN(test.cdn)); err != nil {
+				t.Fatal(err)
+			}
+
+			image := parse.Find(document, parse.ImageKind).(*parse.ImageNode)
+			if image.Link != test.link {
+				t.Errorf("got %q, want %q", image.Link, test.link)
+			}
+		})
+	}
+}
+
+func TestImageCDNLeavesLinksAlone(t *testing.T) {
+	document := parseMarkdown("[page](page.html)", 0)
+	if err := parse.Transform(document, parse.ImageCDN("https://cdn.example.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	if link := parse.Find(document, parse.LinkKind).(*parse.LinkNode); link.Link != "page.html" {
+		t.Errorf("got %q, want %q", link.Link, "page.html")
+	}
+}
+
+func TestImageCDNInvalidUrl(t *testing.T) {
+	document := parseMarkdown("![logo](logo.png)", 0)
+	if err := parse.Transform(document, parse.ImageCDN("http://cdn example.com/%zz")); err == nil {
+		t.Error("expected an error for an invalid CDN URL")
+	}
+}
@@ -0,0 +1,97 @@
+package parse
+
+// WalkStatus tells Walk how to carry on after visiting a node.
+type WalkStatus int
+
+const (
+	// WalkContinue goes on to the children of the node, then its siblings.
+	WalkContinue WalkStatus = iota
+	// WalkSkipChildren leaves out the children of the node being entered.
+	WalkSkipChildren
+	// WalkStop ends the walk straight away.
+	WalkStop
+)
+
+// Visitor is called when Walk enters a node and again when it leaves it,
+// with entering set to false. The status returned on leaving is only
+// checked for WalkStop.
+type Visitor func(node Node, entering bool) WalkStatus
+
+// Walk visits node and everything below it depth first, in document order.
+// Walking a document also visits its footnote definitions after the body.
+// It returns WalkStop if the visitor stopped the walk.
+func Walk(node Node, visitor Visitor) WalkStatus {
+	status := visitor(node, true)
+	if status == WalkStop {
+		return WalkStop
+	}
+
+	if status != WalkSkipChildren {
+		for _, child := range walkChildren(node) {
+			if Walk(child, visitor) == WalkStop {
+				return WalkStop
+			}
+		}
+	}
+
+	if visitor(node, false) == WalkStop {
+		return WalkStop
+	}
+
+	return WalkContinue
+}
+
+func walkChildren(node Node) []Node {
+	document, ok := node.(*DocumentNode)
+	if !ok || len(document.Footnotes) == 0 {
+		return node.Children()
+	}
+
+	children := append([]Node(nil), document.Nodes...)
+	for _, footnote := range document.Footnotes {
+		children = append(children, footnote)
+	}
+
+	return children
+}
+
+// FindAll returns every node below and including node that is of one of
+// the given kinds, in document order.
+func FindAll(node Node, kinds ...NodeKind) []Node {
+	var found []Node
+
+	Walk(node, func(node Node, entering bool) WalkStatus {
+		if entering && isKind(node, kinds) {
+			found = append(found, node)
+		}
+		return WalkContinue
+	})
+
+	return found
+}
+
+// Find returns the first node below and including node that is of one of
+// the given kinds, or nil if there is none.
+func Find(node Node, kinds ...NodeKind) Node {
+	var found Node
+
+	Walk(node, func(node Node, entering bool) WalkStatus {
+		if entering && isKind(node, kinds) {
+			found = node
+			return WalkStop
+		}
+		return WalkContinue
+	})
+
+	return found
+}
+
+func isKind(node Node, kinds []NodeKind) bool {
+	for _, kind := range kinds {
+		if node.Kind() == kind {
+			return true
+		}
+	}
+
+	return false
+}
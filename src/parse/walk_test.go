@@ -0,0 +1,209 @@
+package parse_test
+
+import (
+	"allium/src/parse"
+	"strings"
+	"testing"
+)
+
+const walkSource = "# a\n\n> b *c*\n>\n> - d\n\ne[^1]\n\n[^1]: f\n"
+
+// trace walks the document, recording each node entered as +Kind and left
+// as -Kind, and asking status what to do next.
+func trace(node parse.Node, status func(node parse.Node, entering bool) parse.WalkStatus) (string, parse.WalkStatus) {
+	var steps []string
+	result := parse.Walk(node, func(node parse.Node, entering bool) parse.WalkStatus {
+		if entering {
+			steps = append(steps, "+"+node.Kind().String())
+		} else {
+			steps = append(steps, "-"+node.Kind().String())
+		}
+		return status(node, entering)
+	})
+
+	return strings.Join(steps, " "), result
+}
+
+// textOf joins the text below node.
+func textOf(node parse.Node) string {
+	var text strings.Builder
+	for _, node := range parse.FindAll(node, parse.TextKind) {
+		text.WriteString(node.(*parse.TextNode).Content)
+	}
+
+	return text.String()
+}
+
+func TestWalk(t *testing.T) {
+	document := parseMarkdown(walkSource, parse.FootnotesExtension)
+
+	tests := []struct {
+		name   string
+		status func(node parse.Node, entering bool) parse.WalkStatus
+		trace  string
+		result parse.WalkStatus
+	}{
+		{
+			name: "everything",
+			status: func(node parse.Node, entering bool) parse.WalkStatus {
+				return parse.WalkContinue
+			},
+			trace: "+Document +Header +Text -Text -Header " +
+				"+BlockQuote +Paragraph +Text -Text +Italic +Text -Text -Italic -Paragraph " +
+				"+List +ListItem +Paragraph +Text -Text -Paragraph -ListItem -List -BlockQuote " +
+				"+Paragraph +Text -Text +FootnoteReference -FootnoteReference -Paragraph " +
+				"+FootnoteDefinition +Paragraph +Text -Text -Paragraph -FootnoteDefinition -Document",
+			result: parse.WalkContinue,
+		},
+		{
+			name: "skip block quotes",
+			status: func(node parse.Node, entering bool) parse.WalkStatus {
+				if node.Kind() == parse.BlockQuoteKind {
+					return parse.WalkSkipChildren
+				}
+				return parse.WalkContinue
+			},
+			trace: "+Document +Header +Text -Text -Header +BlockQuote -BlockQuote " +
+				"+Paragraph +Text -Text +FootnoteReference -FootnoteReference -Paragraph " +
+				"+FootnoteDefinition +Paragraph +Text -Text -Paragraph -FootnoteDefinition -Document",
+			result: parse.WalkContinue,
+		},
+		{
+			name: "skip paragraphs",
+			status: func(node parse.Node, entering bool) parse.WalkStatus {
+				if node.Kind() == parse.ParagraphKind {
+					return parse.WalkSkipChildren
+				}
+				return parse.WalkContinue
+			},
+			trace: "+Document +Header +Text -Text -Header " +
+				"+BlockQuote +Paragraph -Paragraph +List +ListItem +Paragraph -Paragraph -ListItem -List -BlockQuote " +
+				"+Paragraph -Paragraph +FootnoteDefinition +Paragraph -Paragraph -FootnoteDefinition -Document",
+			result: parse.WalkContinue,
+		},
+		{
+			name: "skip children when leaving",
+			status: func(node parse.Node, entering bool) parse.WalkStatus {
+				if !entering {
+					return parse.WalkSkipChildren
+				}
+				return parse.WalkContinue
+			},
+			trace: "+Document +Header +Text -Text -Header " +
+				"+BlockQuote +Paragraph +Text -Text +Italic +Text -Text -Italic -Paragraph " +
+				"+List +ListItem +Paragraph +Text -Text -Paragraph -ListItem -List -BlockQuote " +
+				"+Paragraph +Text -Text +FootnoteReference -FootnoteReference -Paragraph " +
+				"+FootnoteDefinition +Paragraph +Text -Text -Paragraph -FootnoteDefinition -Document",
+			result: parse.WalkContinue,
+		},
+		{
+			name: "stop entering",
+			status: func(node parse.Node, entering bool) parse.WalkStatus {
+				if entering && node.Kind() == parse.ItalicKind {
+					return parse.WalkStop
+				}
+				return parse.WalkContinue
+			},
+			trace:  "+Document +Header +Text -Text -Header +BlockQuote +Paragraph +Text -Text +Italic",
+			result: parse.WalkStop,
+		},
+		{
+			name: "stop leaving",
+			status: func(node parse.Node, entering bool) parse.WalkStatus {
+				if !entering && node.Kind() == parse.HeaderKind {
+					return parse.WalkStop
+				}
+				return parse.WalkContinue
+			},
+			trace:  "+Document +Header +Text -Text -Header",
+			result: parse.WalkStop,
+		},
+		{
+			name: "stop in a footnote",
+			status: func(node parse.Node, entering bool) parse.WalkStatus {
+				if entering && node.Kind() == parse.FootnoteDefinitionKind {
+					return parse.WalkStop
+				}
+				return parse.WalkContinue
+			},
+			trace: "+Document +Header +Text -Text -Header " +
+				"+BlockQuote +Paragraph +Text -Text +Italic +Text -Text -Italic -Paragraph " +
+				"+List +ListItem +Paragraph +Text -Text -Paragraph -ListItem -List -BlockQuote " +
+				"+Paragraph +Text -Text +FootnoteReference -FootnoteReference -Paragraph " +
+				"+FootnoteDefinition",
+			result: parse.WalkStop,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			steps, result := trace(document, test.status)
+			if steps != test.trace {
+				t.Errorf("got\n%s\nwant\n%s", steps, test.trace)
+			}
+			if result != test.result {
+				t.Errorf("returned %d, want %d", result, test.result)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	document := parseMarkdown(walkSource, parse.FootnotesExtension)
+
+	tests := []struct {
+		kinds []parse.NodeKind
+		text  string
+	}{
+		{[]parse.NodeKind{parse.HeaderKind}, "a"},
+		{[]parse.NodeKind{parse.ItalicKind, parse.ListItemKind}, "c"},
+		{[]parse.NodeKind{parse.ListItemKind, parse.ItalicKind}, "c"},
+		{[]parse.NodeKind{parse.FootnoteDefinitionKind}, "f"},
+		{[]parse.NodeKind{parse.TableKind}, ""},
+		{nil, ""},
+	}
+
+	for _, test := range tests {
+		node := parse.Find(document, test.kinds...)
+		if test.text == "" {
+			if node != nil {
+				t.Errorf("Find(%v) = %s, want nil", test.kinds, node.Kind())
+			}
+			continue
+		}
+		if node == nil || textOf(node) != test.text {
+			t.Errorf("Find(%v) = %v, want the node with text %q", test.kinds, node, test.text)
+		}
+	}
+
+	// the node itself counts
+	paragraph := parse.Find(document, parse.ParagraphKind)
+	if parse.Find(paragraph, parse.ParagraphKind) != paragraph {
+		t.Error("Find did not return the node it started from")
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	document := parseMarkdown(walkSource, parse.FootnotesExtension)
+
+	tests := []struct {
+		kinds []parse.NodeKind
+		texts string
+	}{
+		{[]parse.NodeKind{parse.TextKind}, "a|b |c|d|e|f"},
+		{[]parse.NodeKind{parse.ParagraphKind}, "b c|d|e|f"},
+		{[]parse.NodeKind{parse.ParagraphKind, parse.ItalicKind}, "b c|c|d|e|f"},
+		{[]parse.NodeKind{parse.BlockQuoteKind, parse.ListKind}, "b cd|d"},
+		{[]parse.NodeKind{parse.TableKind}, ""},
+	}
+
+	for _, test := range tests {
+		var texts []string
+		for _, node := range parse.FindAll(document, test.kinds...) {
+			texts = append(texts, textOf(node))
+		}
+		if got := strings.Join(texts, "|"); got != test.texts {
+			t.Errorf("FindAll(%v) found %q, want %q", test.kinds, got, test.texts)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package main
+
+// terminalWidth returns zero where the terminal size cannot be read.
+func terminalWidth() int {
+	return 0
+}